@@ -0,0 +1,171 @@
+package instructor
+
+import (
+	"reflect"
+	"sort"
+)
+
+// FieldInfo describes a field of a struct, as listed by fields(o)
+type FieldInfo struct {
+	Name     string
+	Type     string
+	Tag      string
+	Exported bool
+}
+
+// MethodInfo describes a method, as listed by methods(o)
+type MethodInfo struct {
+	Name      string
+	Signature string // The type of the method, without its receiver, like func(bool) string
+	// PointerReceiver is set for methods with a pointer receiver, which Instructor finds on values too
+	PointerReceiver bool
+}
+
+// FinderInfo describes a registered finder, as listed by finders()
+type FinderInfo struct {
+	Type      string
+	Signature string
+	All       bool // Set for the finders used by findAll
+}
+
+// VarInfo describes a variable in the heap, as listed by vars()
+type VarInfo struct {
+	Name     string
+	Type     string
+	ReadOnly bool
+}
+
+// builtinNames are the names of the built in functions, for completion
+var builtinNames = []string{"converters", "fields", "finders", "methods", "type", "vars"}
+
+// builtin returns the built in function called name, for the sessions of this interpreter. Anything
+// registered with RegisterFunc under the same name takes its place.
+func (i *interpreter) builtin(name string) (reflect.Value, bool) {
+	var fn interface{}
+	switch name {
+	case "type":
+		fn = typeOf
+	case "fields":
+		fn = fieldsOf
+	case "methods":
+		fn = methodsOf
+	case "finders":
+		fn = i.finderList
+	case "converters":
+		fn = i.converterList
+	case "vars":
+		fn = i.varList
+	default:
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(fn), true
+}
+
+// typeOf returns the name of the type of v, like *models.User
+func typeOf(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// fieldsOf lists the fields of the struct v, or the struct it points to, in the order they're declared.
+// Anything that isn't a struct has no fields.
+func fieldsOf(v interface{}) []FieldInfo {
+	fields := make([]FieldInfo, 0)
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fields
+	}
+	for k := 0; k < t.NumField(); k++ {
+		f := t.Field(k)
+		fields = append(fields, FieldInfo{Name: f.Name, Type: f.Type.String(), Tag: string(f.Tag), Exported: f.PkgPath == ""})
+	}
+	return fields
+}
+
+// methodsOf lists the methods that can be called on v, in alphabetical order. That includes the ones with
+// a pointer receiver when v isn't a pointer, since they're found on values too.
+func methodsOf(v interface{}) []MethodInfo {
+	methods := make([]MethodInfo, 0)
+	if v == nil {
+		return methods
+	}
+	t := reflect.TypeOf(v)
+	value := t
+	if t.Kind() == reflect.Ptr {
+		value = t.Elem()
+	} else {
+		t = reflect.PtrTo(t)
+	}
+	for k := 0; k < t.NumMethod(); k++ {
+		m := t.Method(k)
+		_, onValue := value.MethodByName(m.Name)
+		methods = append(methods, MethodInfo{Name: m.Name, Signature: withoutReceiver(m.Type).String(), PointerReceiver: !onValue})
+	}
+	return methods
+}
+
+// withoutReceiver returns the type of the method mtype, without the receiver it takes first
+func withoutReceiver(mtype reflect.Type) reflect.Type {
+	in := make([]reflect.Type, 0, mtype.NumIn()-1)
+	for k := 1; k < mtype.NumIn(); k++ {
+		in = append(in, mtype.In(k))
+	}
+	out := make([]reflect.Type, 0, mtype.NumOut())
+	for k := 0; k < mtype.NumOut(); k++ {
+		out = append(out, mtype.Out(k))
+	}
+	return reflect.FuncOf(in, out, mtype.IsVariadic())
+}
+
+// finderList lists the registered finders, with the ones used by find first, sorted by the type they find
+func (i *interpreter) finderList() []FinderInfo {
+	list := make([]FinderInfo, 0, len(i.finders)+len(i.allFinders))
+	for _, all := range []bool{false, true} {
+		table := i.finders
+		if all {
+			table = i.allFinders
+		}
+		for name, f := range table {
+			// Finders registered with RegisterFinder or RegisterContextFinder are all called with a string id
+			sig := "func(string) (interface {}, error)"
+			if f.fn.IsValid() {
+				sig = f.fn.Type().String()
+			}
+			list = append(list, FinderInfo{Type: name, Signature: sig, All: all})
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].All != list[b].All {
+			return !list[a].All
+		}
+		return list[a].Type < list[b].Type
+	})
+	return list
+}
+
+// converterList lists the names of the registered converters, in alphabetical order
+func (i *interpreter) converterList() []string {
+	names := make([]string, 0, len(i.converters))
+	for name := range i.converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// varList lists the variables in the heap, in alphabetical order
+func (i *interpreter) varList() []VarInfo {
+	vars := make([]VarInfo, 0, len(i.heap))
+	for name := range i.heap {
+		vars = append(vars, VarInfo{Name: name, Type: typeOf(i.heapValue(name)), ReadOnly: i.isReadOnly(name)})
+	}
+	sort.Slice(vars, func(a, b int) bool {
+		return vars[a].Name < vars[b].Name
+	})
+	return vars
+}
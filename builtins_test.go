@@ -0,0 +1,70 @@
+package instructor
+
+import (
+	"testing"
+)
+
+type tagged struct {
+	ID      string `json:"id"`
+	Owner   *Order
+	private int
+}
+
+func TestBuiltins(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterFinder("testRecord", lookup)
+	i.RegisterFinderFunc("Order", func(id string, n int) (*Order, error) { return nil, nil })
+	i.RegisterMultiFinder("Order", func(id string) []*Order { return nil })
+	i.RegisterConverter("Flooper", convertFloop)
+	i.RegisterGlobal("db", &tagged{}, true)
+	i.storeInHeap("o", &Order{ID: "x"})
+	i.storeInHeap("c", counter{})
+	i.storeInHeap("tg", tagged{})
+
+	checkResults(t, i, []evalCase{
+		{"type(o)", "*instructor.Order"},
+		{"type(c)", "instructor.counter"},
+		{"type(o.ID)", "string"},
+		{"fields(tg)", []FieldInfo{
+			{Name: "ID", Type: "string", Tag: `json:"id"`, Exported: true},
+			{Name: "Owner", Type: "*instructor.Order", Exported: true},
+			{Name: "private", Type: "int"},
+		}},
+		{"fields(o.ID)", []FieldInfo{}},
+		{"methods(c)", []MethodInfo{
+			{Name: "Copy", Signature: "func() instructor.counter"},
+			{Name: "Incr", Signature: "func() int", PointerReceiver: true},
+		}},
+		{"methods(o)", []MethodInfo{{Name: "CustomID", Signature: "func(bool) string", PointerReceiver: true}}},
+		{"finders()", []FinderInfo{
+			{Type: "Order", Signature: "func(string, int) (*instructor.Order, error)"},
+			{Type: "testRecord", Signature: "func(string) (interface {}, error)"},
+			{Type: "Order", Signature: "func(string) []*instructor.Order", All: true},
+		}},
+		{"converters()[0]", "*bool"},
+		{"vars()", []VarInfo{
+			{Name: "c", Type: "instructor.counter"},
+			{Name: "db", Type: "*instructor.tagged", ReadOnly: true},
+			{Name: "o", Type: "*instructor.Order"},
+			{Name: "tg", Type: "instructor.tagged"},
+		}},
+	})
+
+	// What the built ins return can be kept and used like anything else
+	if _, err := evalString(i, "fs = fields(tg)"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := evalString(i, "fs[0].Tag"); err != nil || r != `json:"id"` {
+		t.Errorf("Expected to be able to dig into stored fields, got %#v %v", r, err)
+	}
+	checkFailures(t, i, []string{
+		"fields()",
+		"fields(o, o)",
+		"vars(o)",
+	})
+	// Registered functions take the place of the built in ones
+	i.RegisterFunc("type", func() string { return "mine" })
+	if r, err := evalString(i, "type()"); err != nil || r != "mine" {
+		t.Errorf("Expected a registered function to replace the built in type, got %#v %v", r, err)
+	}
+}
@@ -0,0 +1,42 @@
+// Command instructor-client connects to an Instructor that is being served from inside of a
+// running application, via Instructor.Serve, and drops you into its REPL.
+//
+//	instructor-client -network unix -addr /tmp/myapp.sock
+//	instructor-client -network tcp -addr localhost:4040
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+)
+
+func main() {
+	network := flag.String("network", "unix", "The network the Instructor is served on, unix or tcp")
+	addr := flag.String("addr", "", "The address the Instructor is served on, a socket path or host:port")
+	flag.Parse()
+	if *addr == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial(*network, *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Send everything typed along to the session, and let it know once we're out of input
+	go func() {
+		io.Copy(conn, os.Stdin)
+		if c, ok := conn.(interface{ CloseWrite() error }); ok {
+			c.CloseWrite()
+		}
+	}()
+	// Print everything the session sends back, until it hangs up
+	if _, err := io.Copy(os.Stdout, conn); err != nil {
+		log.Fatal(err)
+	}
+}
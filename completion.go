@@ -0,0 +1,143 @@
+package instructor
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// keywords are always offered when completing at the start of an expression
+var keywords = []string{"find(", "findAll(", "new(", "help", "quit"}
+
+// complete is a word completer for the terminal. It completes the expression under the cursor with finder
+// names inside of find( or findAll(, or type names inside of new(. Anywhere else, it completes registered and
+// built in functions, along with the exported properties and methods of whatever a property chain resolves
+// to, or the variables in the heap.
+func (i *interpreter) complete(line string, pos int) (string, []string, string) {
+	runes := []rune(line)
+	before, tail := string(runes[:pos]), string(runes[pos:])
+	start := expressionStart(before)
+	head, word := before[:start], before[start:]
+
+	candidates := make([]string, 0)
+	opened := strings.TrimRight(head, " ")
+	if !strings.HasSuffix(opened, "find(") && !strings.HasSuffix(opened, "findAll(") && !strings.HasSuffix(opened, "new(") {
+		for name := range i.funcs {
+			candidates = append(candidates, name+"(")
+		}
+		for _, name := range builtinNames {
+			candidates = append(candidates, name+"(")
+		}
+	}
+	if dot := strings.LastIndex(word, "."); dot >= 0 {
+		recv := word[:dot]
+		for _, name := range i.memberNames(recv) {
+			candidates = append(candidates, recv+"."+name)
+		}
+	} else if strings.HasSuffix(opened, "find(") {
+		for name := range i.finders {
+			candidates = append(candidates, name)
+		}
+	} else if strings.HasSuffix(opened, "findAll(") {
+		for name := range i.allFinders {
+			candidates = append(candidates, name)
+		}
+	} else if strings.HasSuffix(opened, "new(") {
+		for name := range i.types {
+			candidates = append(candidates, name)
+		}
+	} else {
+		for name := range i.types {
+			candidates = append(candidates, name+"{")
+		}
+		for name := range i.heap {
+			candidates = append(candidates, name)
+		}
+		candidates = append(candidates, keywords...)
+	}
+
+	completions := make([]string, 0)
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			completions = append(completions, c)
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+// expressionStart walks backwards from the end of s, over the property chain the cursor is in, returning
+// the index it starts at. Indexes are skipped over whole, so o.Orders[1].Cu is all one expression.
+func expressionStart(s string) int {
+	start := len(s)
+	for start > 0 {
+		c := rune(s[start-1])
+		if isLetter(c) || isDigit(c) || c == '.' {
+			start--
+		} else if c == ']' {
+			open := strings.LastIndex(s[:start], "[")
+			if open < 0 {
+				break
+			}
+			start = open
+		} else {
+			break
+		}
+	}
+	return start
+}
+
+// memberNames returns the exported properties and methods of whatever the property chain in expr
+// resolves to, with methods ending in an open paren. Only plain property chains are looked at, since
+// anything with a method call in it could have side effects just from pressing tab.
+func (i *interpreter) memberNames(expr string) []string {
+	n, err := parseStatement(lexStatement(expr))
+	if err != nil || n == nil || !isPropertyChain(n) {
+		return nil
+	}
+	v, err := i.evaluate(n)
+	if err != nil {
+		return nil
+	}
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	names := make([]string, 0)
+	t := v.Type()
+	// Methods with a pointer receiver can be called on values too
+	mt := t
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		mt = reflect.PtrTo(t)
+	}
+	for j := 0; j < mt.NumMethod(); j++ {
+		names = append(names, mt.Method(j).Name+"(")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		for j := 0; j < t.NumField(); j++ {
+			if f := t.Field(j); f.PkgPath == "" {
+				names = append(names, f.Name)
+			}
+		}
+	}
+	return names
+}
+
+// isPropertyChain reports if n is nothing but variables, properties, and literal indexes
+func isPropertyChain(n node) bool {
+	switch n := n.(type) {
+	case *identNode:
+		return true
+	case *fieldNode:
+		return isPropertyChain(n.target)
+	case *indexNode:
+		_, ok := n.index.(*literalNode)
+		return ok && isPropertyChain(n.target)
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package instructor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterFinder("testRecord", lookup)
+	i.RegisterFinder("Order", lookup)
+	obj, _ := lookup("smedley@gmail.com")
+	i.storeInHeap("o", obj)
+	i.storeInHeap("other", 5)
+	i.storeInHeap("ov", Order{})
+	i.RegisterFunc("hash", func(s string) string { return s })
+	i.RegisterFunc("services.Reindex", func() {})
+	i.RegisterType(Order{})
+	i.RegisterMultiFinder("Orders", func(n int) []Order { return nil })
+
+	cases := []struct {
+		line        string
+		head        string
+		completions []string
+	}{
+		{line: "o", head: "", completions: []string{"o", "other", "ov"}},
+		{line: "ov.C", head: "", completions: []string{"ov.CustomID("}},
+		{line: "x = ot", head: "x = ", completions: []string{"other"}},
+		{line: "q", head: "", completions: []string{"quit"}},
+		{line: "x = ha", head: "x = ", completions: []string{"hash("}},
+		{line: "services.R", head: "", completions: []string{"services.Reindex("}},
+		{line: "x = find(te", head: "x = find(", completions: []string{"testRecord"}},
+		{line: "x = fi", head: "x = ", completions: []string{"fields(", "find(", "findAll(", "finders("}},
+		{line: "x = findAll(O", head: "x = findAll(", completions: []string{"Orders"}},
+		{line: "x = new(O", head: "x = new(", completions: []string{"Order"}},
+		{line: "x = Or", head: "x = ", completions: []string{"Order{"}},
+		{line: "o.D", head: "", completions: []string{"o.Dumb"}},
+		{line: "o.S", head: "", completions: []string{"o.Stuff(", "o.Stuff2("}},
+		{line: "o.Dumb.DeepStuff2(o.Orders[1].Cu", head: "o.Dumb.DeepStuff2(", completions: []string{"o.Orders[1].CustomID("}},
+		{line: "o.OrderList().I", head: "o.OrderList()", completions: []string{}},
+	}
+	for _, c := range cases {
+		head, completions, tail := i.complete(c.line+" tail", len([]rune(c.line)))
+		if head != c.head || tail != " tail" || !reflect.DeepEqual(completions, c.completions) {
+			t.Errorf("%s: got %q %q %q, expected %q %q", c.line, head, completions, tail, c.head, c.completions)
+		}
+	}
+}
@@ -1,6 +1,150 @@
 package instructor
 
-import "strconv"
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var timeType = reflect.TypeOf(time.Time{})
+
+// converterNames returns the names a converter for t could be registered under, from the most specific to
+// the least. A named type can be registered by its full package path, like github.com/you/app/models.ID,
+// by the name it prints as, like models.ID, or just by its own name, like ID. Pointers to named types
+// have a * in front of any of those.
+func converterNames(t reflect.Type) []string {
+	prefix := ""
+	for t.Kind() == reflect.Ptr && t.Name() == "" {
+		prefix += "*"
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" {
+		// Built in types, and unnamed ones like []string, only have the one name
+		return []string{prefix + t.String()}
+	}
+	return []string{prefix + t.PkgPath() + "." + t.Name(), prefix + t.String(), prefix + t.Name()}
+}
+
+// converterFor returns the registered converter for t, along with the name it was registered under
+func (i *interpreter) converterFor(t reflect.Type) (Converter, string, bool) {
+	for _, name := range converterNames(t) {
+		if c, ok := i.converters[name]; ok {
+			return c, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// derivedConverter works out how to convert a string to a t from its kind, for types that don't have a
+// converter registered. That covers any type based on a bool, string, int, uint or float, []byte,
+// time.Duration, and time.Time in RFC3339. Structs, maps, slices and arrays are unmarshalled from JSON,
+// and pointers are converted like the type they point to. It returns nil for anything else.
+func (i *interpreter) derivedConverter(t reflect.Type) Converter {
+	switch {
+	case t == durationType:
+		return func(s string) (interface{}, error) {
+			if d, err := time.ParseDuration(s); err == nil {
+				return d, nil
+			}
+			// A plain number is a number of nanoseconds, like it would be in Go
+			n, err := strconv.ParseInt(s, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a duration", s)
+			}
+			return time.Duration(n), nil
+		}
+	case t == timeType:
+		return func(s string) (interface{}, error) {
+			return time.Parse(time.RFC3339, s)
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return func(s string) (interface{}, error) {
+			return reflect.ValueOf([]byte(s)).Convert(t).Interface(), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(s string) (interface{}, error) {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, err
+			}
+			return reflect.ValueOf(b).Convert(t).Interface(), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(s string) (interface{}, error) {
+			n, err := strconv.ParseInt(s, 0, t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetInt(n)
+			return v.Interface(), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(s string) (interface{}, error) {
+			n, err := strconv.ParseUint(s, 0, t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetUint(n)
+			return v.Interface(), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(s string) (interface{}, error) {
+			f, err := strconv.ParseFloat(s, t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetFloat(f)
+			return v.Interface(), nil
+		}
+	case reflect.String:
+		return func(s string) (interface{}, error) {
+			return reflect.ValueOf(s).Convert(t).Interface(), nil
+		}
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return func(s string) (interface{}, error) {
+			p := reflect.New(t)
+			if err := json.Unmarshal([]byte(s), p.Interface()); err != nil {
+				return nil, err
+			}
+			return p.Elem().Interface(), nil
+		}
+	case reflect.Ptr:
+		elem, _, ok := i.converterFor(t.Elem())
+		if !ok {
+			elem = i.derivedConverter(t.Elem())
+		}
+		if elem == nil {
+			return nil
+		}
+		return func(s string) (interface{}, error) {
+			iv, err := elem(s)
+			if err != nil {
+				return nil, err
+			}
+			v := reflect.ValueOf(iv)
+			if v.IsValid() && v.Type().AssignableTo(t) {
+				// Converters registered for the type itself are allowed to return a pointer to it already
+				return iv, nil
+			}
+			if !v.IsValid() || !v.Type().ConvertibleTo(t.Elem()) {
+				return nil, fmt.Errorf("the converter for %s returned a %T", t.Elem(), iv)
+			}
+			p := reflect.New(t.Elem())
+			p.Elem().Set(v.Convert(t.Elem()))
+			return p.Interface(), nil
+		}
+	}
+	return nil
+}
 
 func stringToBool(s string) (interface{}, error) {
 	return strconv.ParseBool(s)
@@ -27,14 +171,19 @@ func stringToPInt(s string) (interface{}, error) {
 }
 
 func stringToUint(s string) (interface{}, error) {
-	return strconv.ParseUint(s, 10, 64)
+	u, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return nil, err
+	}
+	return uint(u), nil
 }
 
 func stringToPUint(s string) (interface{}, error) {
-	i, err := strconv.ParseUint(s, 10, 64)
+	u, err := strconv.ParseUint(s, 10, 0)
 	if err != nil {
 		return nil, err
 	}
+	i := uint(u)
 	return &i, nil
 }
 
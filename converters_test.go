@@ -0,0 +1,113 @@
+package instructor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type level int8
+type tag string
+type ratio float32
+type blob []byte
+
+func TestConverterNames(t *testing.T) {
+	pkg := reflect.TypeOf(Flooper{}).PkgPath()
+	cases := []struct {
+		sample interface{}
+		names  []string
+	}{
+		{0, []string{"int"}},
+		{new(int), []string{"*int"}},
+		{[]string{}, []string{"[]string"}},
+		{Flooper{}, []string{pkg + ".Flooper", "instructor.Flooper", "Flooper"}},
+		{&Flooper{}, []string{"*" + pkg + ".Flooper", "*instructor.Flooper", "*Flooper"}},
+	}
+	for _, c := range cases {
+		if names := converterNames(reflect.TypeOf(c.sample)); !reflect.DeepEqual(names, c.names) {
+			t.Errorf("%T: got %q, expected %q", c.sample, names, c.names)
+		}
+	}
+}
+
+func TestDerivedConverters(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterFunc("level", func(l level) level { return l })
+	i.RegisterFunc("tag", func(t tag) tag { return t })
+	i.RegisterFunc("ratio", func(r ratio) ratio { return r })
+	i.RegisterFunc("small", func(n int16, u uint8) int { return int(n) + int(u) })
+	i.RegisterFunc("blob", func(b blob) int { return len(b) })
+	i.RegisterFunc("wait", func(d time.Duration) time.Duration { return d })
+	i.RegisterFunc("year", func(t time.Time) int { return t.Year() })
+	i.RegisterFunc("floops", func(f *Flooper) int { return f.Floops })
+	i.RegisterFunc("sum", func(m map[string]int) int { return m["a"] + m["b"] })
+	i.RegisterFunc("count", func(pn *int8) int8 { return *pn })
+	i.RegisterFunc("unsigned", func(u uint, pu *uint) uint { return u + *pu })
+	checkResults(t, i, []evalCase{
+		{"level(\"-3\")", level(-3)},
+		{"level(7)", level(7)},
+		{"tag(\"x\")", tag("x")},
+		{"ratio(\"0.5\")", ratio(0.5)},
+		{"small(\"0x10\", \"255\")", 271},
+		{"blob(\"abc\")", 3},
+		{"wait(\"1m30s\")", 90 * time.Second},
+		{"wait(5)", time.Duration(5)},
+		{"year(\"2026-01-02T15:04:05Z\")", 2026},
+		{"floops(`{\"floops\": 5}`)", 5},
+		{"sum(`{\"a\": 1, \"b\": 2}`)", 3},
+		{"count(\"12\")", int8(12)},
+		{"count(&12)", int8(12)},
+		{"unsigned(5, 6)", uint(11)},
+		{"unsigned(\"5\", &6)", uint(11)},
+	})
+
+	var conversionErr *ConversionError
+	for _, s := range []string{
+		"small(\"70000\", 1)",
+		"small(1, \"-1\")",
+		"small(70000, 1)",
+		"small(1, 300)",
+		"count(&300)",
+		"wait(\"soon\")",
+		"year(\"yesterday\")",
+		"floops(`{\"floops\": \"a\"}`)",
+	} {
+		if _, err := evalString(i, s); !errors.As(err, &conversionErr) {
+			t.Errorf("%s: expected a ConversionError, got %#v", s, err)
+		}
+	}
+}
+
+func TestRegisteredConverterNames(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterFunc("floops", func(f Flooper) int { return f.Floops })
+	convertTo := func(n int) Converter {
+		return func(string) (interface{}, error) { return Flooper{Floops: n}, nil }
+	}
+	// The most specific name wins
+	i.RegisterConverter("Flooper", convertTo(1))
+	i.RegisterConverter("instructor.Flooper", convertTo(2))
+	if r, err := evalString(i, "floops(\"x\")"); err != nil || r != 2 {
+		t.Errorf("Expected the converter registered as instructor.Flooper, got %#v, %v", r, err)
+	}
+	i.RegisterConverter(reflect.TypeOf(Flooper{}).PkgPath()+".Flooper", convertTo(3))
+	if r, err := evalString(i, "floops(\"x\")"); err != nil || r != 3 {
+		t.Errorf("Expected the converter registered by its full path, got %#v, %v", r, err)
+	}
+	// Pointers use the converter for what they point to
+	i.RegisterFunc("pfloops", func(f *Flooper) int { return f.Floops })
+	if r, err := evalString(i, "pfloops(\"x\")"); err != nil || r != 3 {
+		t.Errorf("Expected a pointer to use the converter for Flooper, got %#v, %v", r, err)
+	}
+
+	// Converters for the type are also allowed to return a pointer to it, for pointer parameters
+	i = newInterpreter()
+	i.RegisterFunc("pfloops", func(f *Flooper) int { return f.Floops })
+	i.RegisterConverter("Flooper", func(string) (interface{}, error) {
+		return &Flooper{Floops: 4}, nil
+	})
+	if r, err := evalString(i, "pfloops(\"x\")"); err != nil || r != 4 {
+		t.Errorf("Expected a pointer returned by the Flooper converter to be passed as is, got %#v, %v", r, err)
+	}
+}
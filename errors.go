@@ -0,0 +1,201 @@
+package instructor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ParseError is returned when a statement can't be parsed, pointing at the fragment that didn't fit
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return "Error: " + e.Msg
+}
+
+// Position returns where in the statement the error occurred
+func (e *ParseError) Position() Position {
+	return e.Pos
+}
+
+// UnknownVariableError is returned when a statement refers to a variable that isn't in the heap
+type UnknownVariableError struct {
+	Pos  Position
+	Name string
+}
+
+func (e *UnknownVariableError) Error() string {
+	return fmt.Sprintf("Error: Unknown variable %s", e.Name)
+}
+
+// Position returns where in the statement the error occurred
+func (e *UnknownVariableError) Position() Position {
+	return e.Pos
+}
+
+// UnknownTypeError is returned when building a type that hasn't been registered
+type UnknownTypeError struct {
+	Pos  Position
+	Name string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("Error: No type registered called %s", e.Name)
+}
+
+// Position returns where in the statement the error occurred
+func (e *UnknownTypeError) Position() Position {
+	return e.Pos
+}
+
+// NoConverterError is returned when a literal argument doesn't fit the type of its parameter, and there is
+// no Converter registered for that type to turn it into one
+type NoConverterError struct {
+	Pos  Position
+	Type string
+}
+
+func (e *NoConverterError) Error() string {
+	return fmt.Sprintf("Error: No converter found for type: %s", e.Type)
+}
+
+// Position returns where in the statement the error occurred
+func (e *NoConverterError) Position() Position {
+	return e.Pos
+}
+
+// ConversionError is returned when a literal argument can't be converted to the type of its parameter
+type ConversionError struct {
+	Pos   Position
+	Value string // The text of the literal
+	Type  string
+	Err   error // The error from the converter
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("Error converting %s to %s: %s", e.Value, e.Type, e.Err.Error())
+}
+
+// Unwrap returns the error from the converter
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns where in the statement the error occurred
+func (e *ConversionError) Position() Position {
+	return e.Pos
+}
+
+// MethodNotFoundError is returned when calling a method that the receiver doesn't have
+type MethodNotFoundError struct {
+	Pos      Position
+	Receiver string // The expression the method was called on
+	Type     string // The type that expression resolved to
+	Method   string
+}
+
+func (e *MethodNotFoundError) Error() string {
+	return fmt.Sprintf("Error: %s (%s) has no method %s", e.Receiver, e.Type, e.Method)
+}
+
+// Position returns where in the statement the error occurred
+func (e *MethodNotFoundError) Position() Position {
+	return e.Pos
+}
+
+// MissingKeyError is returned when indexing a map with a key it doesn't have, rather than giving back
+// the zero value like Go would, so you can tell the two apart
+type MissingKeyError struct {
+	Pos Position
+	Map string      // The expression for the map being indexed
+	Key interface{} // The key that wasn't found
+}
+
+func (e *MissingKeyError) Error() string {
+	return fmt.Sprintf("Error: %s has no key %#v", e.Map, e.Key)
+}
+
+// Position returns where in the statement the error occurred
+func (e *MissingKeyError) Position() Position {
+	return e.Pos
+}
+
+// CallError is returned when a method in the middle of a chain of calls returns a non-nil error,
+// rather than carrying on with whatever else it returned
+type CallError struct {
+	Pos  Position
+	Call string // The call that returned the error
+	Err  error  // The error it returned
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("Error: %s returned an error: %s", e.Call, e.Err.Error())
+}
+
+// Unwrap returns the error that the method returned
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns where in the statement the error occurred
+func (e *CallError) Position() Position {
+	return e.Pos
+}
+
+// CancelledError is returned when a statement is cancelled, by its timeout or by Ctrl-C in the REPL, while
+// waiting on a call. The call itself is left to finish in the background.
+type CancelledError struct {
+	Pos  Position
+	Call string // The call that was being waited on
+	Err  error  // Why the statement's context was cancelled
+}
+
+func (e *CancelledError) Error() string {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return fmt.Sprintf("Error: %s timed out", e.Call)
+	}
+	return fmt.Sprintf("Error: %s was cancelled", e.Call)
+}
+
+// Unwrap returns why the statement's context was cancelled
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns where in the statement the error occurred
+func (e *CancelledError) Position() Position {
+	return e.Pos
+}
+
+// CallPanicError is returned when something panics while being evaluated, usually a method being called,
+// so it doesn't take down Instructor with it
+type CallPanicError struct {
+	Pos   Position
+	Call  string      // The call, or other expression, that panicked
+	Value interface{} // The value that was recovered from the panic
+	Stack string      // The stack trace of the goroutine, from where it panicked
+}
+
+func (e *CallPanicError) Error() string {
+	return fmt.Sprintf("Error: %s panicked: %v", e.Call, e.Value)
+}
+
+// Position returns where in the statement the error occurred
+func (e *CallPanicError) Position() Position {
+	return e.Pos
+}
+
+// panicError builds a CallPanicError for a value recovered while evaluating n. It must be called from
+// the deferred function doing the recovering, so the stack still shows where the panic came from.
+func panicError(n node, r interface{}) error {
+	return &CallPanicError{Pos: n.Pos(), Call: n.String(), Value: r, Stack: string(debug.Stack())}
+}
+
+// positioned is anything that can say where in a statement it came from
+type positioned interface {
+	Position() Position
+}
@@ -0,0 +1,69 @@
+package instructor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTypedErrors(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterFinder("testRecord", lookup)
+	if _, err := evalString(i, "o = find(testRecord, \"smedley@gmail.com\")"); err != nil {
+		t.Fatal(err)
+	}
+
+	var parseErr *ParseError
+	_, err := evalString(i, "o.Stuff(1 2)")
+	if !errors.As(err, &parseErr) || parseErr.Pos != (Position{Line: 1, Column: 11}) {
+		t.Errorf("Expected a ParseError at column 11, got %#v", err)
+	}
+
+	var unknownErr *UnknownVariableError
+	_, err = evalString(i, "o.Dumb.DeepStuff2(true, nope)")
+	if !errors.As(err, &unknownErr) || unknownErr.Name != "nope" || unknownErr.Pos.Column != 25 {
+		t.Errorf("Expected an UnknownVariableError at column 25, got %#v", err)
+	}
+
+	var methodErr *MethodNotFoundError
+	_, err = evalString(i, "o.Dumb.Missing()")
+	if !errors.As(err, &methodErr) || methodErr.Method != "Missing" || methodErr.Receiver != "o.Dumb" || methodErr.Pos.Column != 8 {
+		t.Errorf("Expected a MethodNotFoundError at column 8, got %#v", err)
+	}
+
+	var conversionErr *ConversionError
+	_, err = evalString(i, "o.Dumb.DeepStuff3(5)")
+	if !errors.As(err, &conversionErr) || conversionErr.Type != "instructor.Flooper" || conversionErr.Pos.Column != 19 {
+		t.Errorf("Expected a ConversionError at column 19, got %#v", err)
+	}
+
+	for _, s := range []string{"x = 9999999999999999999999", "z = 1.5.6"} {
+		if _, err = evalString(i, s); !errors.As(err, &conversionErr) || conversionErr.Pos.Column != 5 {
+			t.Errorf("%s: expected a ConversionError at column 5, got %#v", s, err)
+		}
+	}
+
+	var converterErr *NoConverterError
+	i.RegisterFunc("apply", func(f func()) {})
+	_, err = evalString(i, "apply(5)")
+	if !errors.As(err, &converterErr) || converterErr.Type != "func()" || converterErr.Pos.Column != 7 {
+		t.Errorf("Expected a NoConverterError at column 7, got %#v", err)
+	}
+
+	var panicErr *CallPanicError
+	i.storeInHeap("p", (*int)(nil))
+	_, err = evalString(i, "o.Dumb.DeepStuff4(p)")
+	if !errors.As(err, &panicErr) || panicErr.Call != "o.Dumb.DeepStuff4(p)" || !strings.Contains(panicErr.Stack, "DeepStuff4") {
+		t.Errorf("Expected a CallPanicError with a stack trace, got %#v", err)
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	out := &bytes.Buffer{}
+	printError(out, "\to.Dumb.Missing()", &MethodNotFoundError{Pos: Position{Line: 1, Column: 8}, Receiver: "o.Dumb", Type: "nestedProperty", Method: "Missing"})
+	expected := "!!   o.Dumb.Missing()\n!!          ^\n!! Error: o.Dumb (nestedProperty) has no method Missing\n"
+	if out.String() != expected {
+		t.Errorf("Got:\n%s\nExpected:\n%s", out, expected)
+	}
+}
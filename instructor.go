@@ -3,43 +3,75 @@ package instructor
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"reflect"
 	"strings"
+	"time"
 )
 
 // Finder is a function type that is used to load an object, serialized into a struct
 // from the integrating-applications list of structs
 type Finder func(string) (interface{}, error)
 
+// ContextFinder is a Finder that also gets the context of the statement it's called from, which is
+// cancelled when the statement times out, or is interrupted with Ctrl-C in the REPL
+type ContextFinder func(context.Context, string) (interface{}, error)
+
 // Converter is a function type that is used to convert a string to an associated type
 // You'd wrap whatever logic you needed, including something like just JSON Unmarshalling
 // to turn a string representation of a value into a concrete instance of it's type.
-// Four come out of the box for you: string, bool, int, and float64
+// Most types can be converted without one, see RegisterConverter
 type Converter func(string) (interface{}, error)
 
 // Internal types used to be more explicit about the purposes of these maps
 type heap map[string]interface{}
-type finders map[string]Finder
+type addressed map[string]bool
+type finders map[string]finder
+type funcs map[string]reflect.Value
+type globals map[string]global
+type types map[string]reflect.Type
 type converters map[string]Converter
 type fragment struct {
 	token Token
 	text  string
+	pos   Position
 }
 type statement []fragment
 
+// finder is a registered finder. The ones registered with RegisterFinder or RegisterContextFinder take
+// their id as a string, and any other function takes whatever arguments it declares
+type finder struct {
+	byID ContextFinder
+	fn   reflect.Value
+}
+
+// global is a variable the integrating application has put in every session's heap
+type global struct {
+	value    interface{}
+	readOnly bool
+}
+
+// lastErrVariable is the variable the most recent error in a session is stored in
+const lastErrVariable = "lasterr"
+
 // Version is the current semver for this tool
 const Version = "0.1.9"
 
 // Instructor is an instance of the object which will allow you to inspect structs
 type Instructor struct {
-	interpreter *interpreter
+	interpreter     *interpreter
+	continueOnError bool
+	historyFile     string
 }
 
 // New returns a new Instructor
 func New() *Instructor {
 	return &Instructor{
 		interpreter: newInterpreter(),
+		historyFile: defaultHistoryFile(),
 	}
 }
 
@@ -48,50 +80,241 @@ func (i *Instructor) RegisterFinder(name string, f Finder) {
 	i.interpreter.RegisterFinder(name, f)
 }
 
-// RegisterConverter is for registering one of your custom converters to convert cli arguments to typed values
+// RegisterContextFinder is like RegisterFinder, for finders that should stop what they're doing when the
+// statement that called them is cancelled, like ones that query a database
+func (i *Instructor) RegisterContextFinder(name string, f ContextFinder) {
+	i.interpreter.RegisterContextFinder(name, f)
+}
+
+// RegisterFinderFunc registers any function as the finder for a type, so find(Order, customerID, "2026-01-01")
+// calls it with those arguments, converted the same way as a method's. If its first parameter is a
+// context.Context, it gets the statement's context. It has to return the value it found, and optionally an
+// error, which fails the statement when it isn't nil.
+func (i *Instructor) RegisterFinderFunc(name string, fn interface{}) error {
+	return i.interpreter.RegisterFinderFunc(name, fn)
+}
+
+// RegisterMultiFinder registers a function that findAll uses to look up a list of a type, like
+// findAll(Order, customerID). It works like RegisterFinderFunc, except that it has to return a slice.
+func (i *Instructor) RegisterMultiFinder(name string, fn interface{}) error {
+	return i.interpreter.RegisterMultiFinder(name, fn)
+}
+
+// RegisterFunc is for registering any Go function, so it can be called directly in a session, like hash("x")
+// or now(). The name can have a package in front of it, like services.Reindex, which is looked up before any
+// variable of the same name. Arguments are checked and converted the same way they are for methods.
+func (i *Instructor) RegisterFunc(name string, fn interface{}) error {
+	return i.interpreter.RegisterFunc(name, fn)
+}
+
+// Set stores v in a variable called name, which is there from the start of every session, including the ones
+// attached with Serve or RunSession. Use it to hand sessions your DB handle, config, service singletons, caches,
+// and so on. A session can reassign the variable, which only changes what it holds in that session's heap. REPL
+// and RunScript share a heap, so a reassignment in one is seen by the other.
+func (i *Instructor) Set(name string, v interface{}) {
+	i.RegisterGlobal(name, v, false)
+}
+
+// RegisterGlobal is like Set, but when readOnly is true sessions can't reassign the variable, or change
+// anything stored in it by value. Anything reached through a pointer can still be changed.
+func (i *Instructor) RegisterGlobal(name string, v interface{}, readOnly bool) {
+	i.interpreter.RegisterGlobal(name, v, readOnly)
+}
+
+// RegisterType makes the type of sample available to sessions by its name, so they can build new ones with
+// User{Name: "a", Age: 3} or new(User), without needing a Finder for them. sample can be a value of the
+// type, or a pointer to one. Only one type can be registered under each name, so registering models.User
+// and auth.User returns an error for the second.
+func (i *Instructor) RegisterType(sample interface{}) error {
+	return i.interpreter.RegisterType(sample)
+}
+
+// RegisterConverter is for registering one of your custom converters to convert cli arguments to typed values.
+// Register it under the full package path of the type, like github.com/you/app/models.ID, so it can't be
+// mixed up with any other type named ID. The shorter models.ID or ID work too, for as long as they're unique.
+// Types without a converter are converted based on their kind, which covers anything based on a bool, string,
+// int, uint or float, along with []byte, time.Duration, time.Time in RFC3339, and JSON for structs, maps
+// and slices.
 func (i *Instructor) RegisterConverter(name string, c Converter) {
 	i.interpreter.RegisterConverter(name, c)
 }
 
-// REPL will enter the read eval print loop, blocking the main thread until it exits
+// SetContinueOnError controls whether RunScript keeps going after a statement fails. By default, a
+// script stops at the first statement that fails.
+func (i *Instructor) SetContinueOnError(c bool) {
+	i.continueOnError = c
+}
+
+// SetRaiseErrors controls what happens when a method returns a non-nil error as its last result. By default
+// it's returned like any other value, so v, err = o.Load(5) works like it would in Go. When raising errors,
+// it fails the statement instead, and the error result is left off of what the method returns.
+func (i *Instructor) SetRaiseErrors(r bool) {
+	i.interpreter.raiseErrors = r
+}
+
+// SetTimeout limits how long each statement can run for. Every statement is evaluated with a context.Context,
+// which is cancelled once the timeout passes, or when you press Ctrl-C in the REPL. It's handed to any method
+// or function whose first parameter is a context.Context, and to finders registered with
+// RegisterContextFinder. A timeout of 0, the default, means statements can run for as long as they like.
+func (i *Instructor) SetTimeout(d time.Duration) {
+	i.interpreter.timeout = d
+}
+
+// SetHistoryFile sets where the REPL saves the statements you've entered, so they can be recalled in later
+// sessions. It defaults to .instructor_history in your home directory, and an empty path turns it off.
+func (i *Instructor) SetHistoryFile(path string) {
+	i.historyFile = path
+}
+
+// REPL will enter the read eval print loop, blocking the main thread until it exits. When attached to a
+// terminal, you get line editing, tab completion, and history that's saved between sessions.
 func (i *Instructor) REPL() error {
-	// Buffered reader off of STDIN
-	reader := bufio.NewReader(os.Stdin)
-	input := ""
+	t := newTerminal(i.interpreter, i.historyFile)
+	defer t.close()
+	// Ctrl-C cancels whatever statement is running, rather than the whole process
+	interp := i.interpreter.withOutput(i.interpreter.out, i.interpreter.errOut)
+	interp.interruptible = true
+	return runSession(interp, t.readLine)
+}
+
+// RunSession runs the read eval print loop over the given input and outputs, instead of the terminal,
+// blocking until the session quits or in runs out. Results are written to out, and errors to errOut.
+// This is useful for embedding Instructor in your tests, web consoles, or anywhere else you want to
+// capture what it prints. Like the sessions attached with Serve, each one gets its own heap, which starts
+// out with just the globals, so sessions can safely run at the same time without seeing each other's variables.
+func (i *Instructor) RunSession(in io.Reader, out io.Writer, errOut io.Writer) error {
+	return runSession(i.interpreter.newSession(out, errOut), readerLines(in, out))
+}
+
+// lineReader shows a prompt, and reads the next line of input. It returns io.EOF once the input runs out.
+type lineReader func(prompt string) (string, error)
+
+// readerLines returns a lineReader over plain input, which writes the prompt to out
+func readerLines(in io.Reader, out io.Writer) lineReader {
+	// Buffered reader off of the input
+	reader := bufio.NewReader(in)
+	return func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		return reader.ReadString('\n')
+	}
+}
+
+// runSession runs the read eval print loop for a single session, reading statements with read
+// and writing everything back to the interpreter's outputs, until the session quits or the input runs out
+func runSession(interp *interpreter, read lineReader) error {
+	out := interp.out
 	stop := false
-	var err error
 	// Print welcome message
-	fmt.Printf("Welcome to Inspector v%s\n", Version)
-	fmt.Printf("For a list of commands, type help\n")
+	fmt.Fprintf(out, "Welcome to Inspector v%s\n", Version)
+	fmt.Fprintf(out, "For a list of commands, type help\n")
 	for !stop {
-		fmt.Printf("instructor %s >>", Version)
-		if input, err = reader.ReadString('\n'); err != nil {
-			fmt.Printf("Error reading from STDIN: %s\n", err.Error())
-		}
-		input = strings.TrimSpace(input)
-		switch input {
-		case "quit":
+		input, err := read(fmt.Sprintf("instructor %s >>", Version))
+		if err == io.EOF {
+			// Evaluate whatever was left on the final line, then we're done
 			stop = true
-		case "help":
-			fmt.Println("You can call the following commands:")
-			fmt.Println("quit : exits the REPL")
-			fmt.Println("help : prints this screen")
-			fmt.Println("find : Looks up an object by it's type and ID")
-			fmt.Println("\t\tEx: u = find(User,\"123456789\")")
-			fmt.Println("You can call methods or invoke Properties on an object. You can provide arguments by giving their type and value, in the order they're defined on the method")
-			fmt.Println("\t\tEx: u.Strawmethod(false ,50)")
-			fmt.Println("\t\tEc: u.Strawproperty")
-		default:
-			l := newLexer(strings.NewReader(input))
-			var f fragment
-			s := make(statement, 0)
-			for f.token != EOF {
-				f = l.scan()
-				s = append(s, f)
-			}
-			// TODO just lass the lexer straight into evaluate
-			i.interpreter.Evaluate(s)
+		} else if err != nil {
+			return fmt.Errorf("Error reading input: %s", err.Error())
+		}
+		quit, err := evaluateInput(interp, input)
+		if err != nil {
+			printError(interp.errOut, input, err)
 		}
+		stop = stop || quit
 	}
 	return nil
 }
+
+// printError writes err to out, with every line marked so errors stand out from results. When the error
+// points at a spot in the input, the input is echoed first, with a caret underneath that spot, and when it
+// came from a panic, the stack trace follows it.
+func printError(out io.Writer, input string, err error) {
+	var p positioned
+	if errors.As(err, &p) {
+		pos := p.Position()
+		lines := strings.Split(strings.TrimSpace(input), "\n")
+		if pos.Line >= 1 && pos.Line <= len(lines) {
+			line := lines[pos.Line-1]
+			fmt.Fprintf(out, "!!   %s\n!!   %s^\n", line, caretPadding(line, pos.Column))
+		}
+	}
+	msg := err.Error()
+	var panicErr *CallPanicError
+	if errors.As(err, &panicErr) {
+		msg += "\n" + strings.TrimSpace(panicErr.Stack)
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		fmt.Fprintf(out, "!! %s\n", line)
+	}
+}
+
+// caretPadding returns the whitespace needed to line a caret up under the given column of line,
+// keeping any tabs so it lines up however wide the terminal shows them
+func caretPadding(line string, column int) string {
+	pad := make([]rune, 0, column)
+	for j, c := range []rune(line) {
+		if j >= column-1 {
+			break
+		}
+		if c == '\t' {
+			pad = append(pad, c)
+		} else {
+			pad = append(pad, ' ')
+		}
+	}
+	return string(pad)
+}
+
+// evaluateInput handles a single line of input, returning true if it was a request to stop
+func evaluateInput(interp *interpreter, input string) (bool, error) {
+	input = strings.TrimSpace(input)
+	switch {
+	case input == "" || isComment(input):
+		// Nothing to evaluate
+	case input == "quit":
+		return true, nil
+	case input == "help":
+		printHelp(interp.out)
+	default:
+		// TODO just lass the lexer straight into evaluate
+		if err := interp.Evaluate(lexStatement(input)); err != nil {
+			// Hang on to the error, so it can be inspected further
+			interp.storeInHeap(lastErrVariable, err)
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// lexStatement scans a line of input into a statement, up to and including the EOF
+func lexStatement(input string) statement {
+	l := newLexer(strings.NewReader(input))
+	var f fragment
+	s := make(statement, 0)
+	for f.token != EOF {
+		f = l.scan()
+		s = append(s, f)
+	}
+	return s
+}
+
+// isComment reports if a line is a comment, which begins with either # or //
+func isComment(input string) bool {
+	return strings.HasPrefix(input, "#") || strings.HasPrefix(input, "//")
+}
+
+func printHelp(out io.Writer) {
+	fmt.Fprintln(out, "You can call the following commands:")
+	fmt.Fprintln(out, "quit : exits the REPL")
+	fmt.Fprintln(out, "help : prints this screen")
+	fmt.Fprintln(out, "find : Looks up an object by it's type and ID")
+	fmt.Fprintln(out, "\t\tEx: u = find(User,\"123456789\")")
+	fmt.Fprintln(out, "findAll : Looks up a list of objects by their type, and whatever else their finder takes")
+	fmt.Fprintln(out, "\t\tEx: os = findAll(Order, u.ID, \"2026-01-01\")")
+	fmt.Fprintln(out, "You can call methods or invoke Properties on an object. You can provide arguments by giving their type and value, in the order they're defined on the method")
+	fmt.Fprintln(out, "\t\tEx: u.Strawmethod(false ,50)")
+	fmt.Fprintln(out, "\t\tEc: u.Strawproperty")
+	fmt.Fprintln(out, "You can also call any function that's been registered, by name")
+	fmt.Fprintln(out, "\t\tEx: services.Reindex(u.ID, true)")
+	fmt.Fprintln(out, "To see what you can do with something, there are a few built in functions")
+	fmt.Fprintln(out, "\t\ttype(u), fields(u), methods(u), finders(), converters(), vars()")
+}
@@ -0,0 +1,70 @@
+package instructor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunSession(t *testing.T) {
+	i := New()
+	i.RegisterFinder("testRecord", lookup)
+	in := strings.NewReader("o = find(testRecord, \"smedley@gmail.com\")\no.Email\nnope\nquit\no.Email\n")
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	if err := i.RunSession(in, out, errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "(string) (len=16) \"smedley@mail.com\"") {
+		t.Errorf("Expected the results in out, got:\n%s", out)
+	}
+	if !strings.Contains(errOut.String(), "Unknown variable nope") {
+		t.Errorf("Expected the error in errOut, got:\n%s", errOut)
+	}
+	// Nothing after quit should have been evaluated
+	if strings.Count(out.String(), "smedley@mail.com") != 2 {
+		t.Errorf("Expected the session to stop at quit, got:\n%s", out)
+	}
+}
+
+func TestLastErr(t *testing.T) {
+	i := New()
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	if err := i.RunSession(strings.NewReader("nope\nlasterr.Name\n"), out, errOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(errOut.String(), "!! ") {
+		t.Errorf("Expected errors to be marked, got:\n%s", errOut)
+	}
+	if !strings.Contains(out.String(), "(string) (len=4) \"nope\"") {
+		t.Errorf("Expected lasterr to hold the error, got:\n%s", out)
+	}
+}
+
+func TestRunSessionHeaps(t *testing.T) {
+	i := New()
+	i.Set("limit", 10)
+	results := make(chan string, 2)
+	for _, input := range []string{"limit = 20\nx = 1\n", "limit = 30\ny = 2\n"} {
+		go func(input string) {
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			i.RunSession(strings.NewReader(input), out, errOut)
+			results <- errOut.String()
+		}(input)
+	}
+	for k := 0; k < 2; k++ {
+		if errOut := <-results; errOut != "" {
+			t.Errorf("Expected sessions to run side by side, got:\n%s", errOut)
+		}
+	}
+
+	// Each session starts out with the globals, and nothing the others did
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	i.RunSession(strings.NewReader("limit\nx\n"), out, errOut)
+	if !strings.Contains(out.String(), "(int) 10") || !strings.Contains(errOut.String(), "Unknown variable x") {
+		t.Errorf("Expected a new session to only have the globals, got:\n%s\n%s", out, errOut)
+	}
+}
@@ -1,10 +1,16 @@
 package instructor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
 	"reflect"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 )
@@ -13,15 +19,35 @@ import (
 // and interprets statements
 type interpreter struct {
 	finders    finders
+	allFinders finders
+	funcs      funcs
+	globals    globals
+	types      types
 	converters converters
 	heap       heap
+	addressed  addressed
+	out        io.Writer
+	errOut     io.Writer
+	// raiseErrors turns a non-nil error returned by a method call into an error from the statement
+	raiseErrors bool
+	// timeout limits how long each statement can run for, if it isn't 0
+	timeout time.Duration
+	// interruptible cancels the statement being run when the process gets an interrupt, like Ctrl-C
+	interruptible bool
+	// ctx is the context of the statement being evaluated
+	ctx context.Context
 }
 
 // newInterpreter returns a new Instructor
 func newInterpreter() *interpreter {
 	return &interpreter{
 		finders: make(finders),
+		funcs:   make(funcs),
+		globals: make(globals),
+		types:   make(types),
 		heap:    make(heap),
+		out:     os.Stdout,
+		errOut:  os.Stderr,
 		converters: map[string]Converter{
 			"bool":     stringToBool,
 			"*bool":    stringToPBool,
@@ -34,312 +60,839 @@ func newInterpreter() *interpreter {
 			"string":   stringToString,
 			"*string":  stringToPString,
 		},
+		allFinders: make(finders),
+		addressed:  make(addressed),
 	}
 }
 
-type statementType int
-
-// These are the list of statementTypes that are currently supported
-const (
-	INVALID      = iota // 0
-	ASSIGNMENT          // 1
-	METHODCALL          // 2
-	PROPERTYCALL        // 3
-	INSPECT             // 4
-	LOOKUP              // 5
-	LITERAL             // 6
-)
+// newSession returns an interpreter with its own heap, holding nothing but the globals, that shares the finders
+// and converters of this one and writes its results to out, and its errors to errOut
+func (i *interpreter) newSession(out io.Writer, errOut io.Writer) *interpreter {
+	s := i.withOutput(out, errOut)
+	s.heap = make(heap)
+	s.addressed = make(addressed)
+	for name, g := range i.globals {
+		s.heap[name] = g.value
+	}
+	return s
+}
 
-type preparedStatement struct {
-	fullStatement statement
-	lhs           statement
-	rhs           statement
-	t             statementType
-}
-
-func (i *interpreter) prepareStatement(s statement) (preparedStatement, error) {
-	// First thing, lets just make sure no pesky whitespace is hanging around
-	s = cleanWhitespace(s)
-	ps := preparedStatement{fullStatement: s}
-	// Fail fast if it's a simple call to find
-	if s[0].token == FIND {
-		ps.t = LOOKUP
-		ps.lhs = s
-		return ps, nil
-	}
-	// Fail fast if it's just inspecting a variable/literal
-	if len(s) == 2 {
-		if s[0].token == VARIABLE {
-			ps.t = INSPECT
-			ps.lhs = s
-			return ps, nil
-		} else if isValueToken(s[0].token) {
-			ps.t = LITERAL
-			ps.lhs = s
-			return ps, nil
-		}
-	}
-	for i := 0; i < len(s); i++ {
-		f := s[i]
-		// If there is a singleequal anywhere, this puts us into a lhs = rhs assignment
-		if f.token == ASSIGN {
-			ps.t = ASSIGNMENT
-			ps.lhs = s[0:i]  // Everything on the left of the = is LHS
-			ps.rhs = s[i+1:] // Everything +1 the current point is the RHS
-			return ps, nil
-		} else if f.token == LPAREN {
-			// If we haven't hit an assignment but there is a ( somewhere, this is a direct method invocation
-			ps.t = METHODCALL
-			ps.lhs = s
-			return ps, nil
-		}
-	}
-	// If it wasn't a variable inspection, and there were no = or ( found, it must be a property invocation
-	ps.t = PROPERTYCALL
-	ps.lhs = s
-	return ps, nil
+// withOutput returns an interpreter that shares everything with this one, including the heap, but
+// writes its results to out, and its errors to errOut
+func (i *interpreter) withOutput(out io.Writer, errOut io.Writer) *interpreter {
+	return &interpreter{
+		finders:     i.finders,
+		allFinders:  i.allFinders,
+		funcs:       i.funcs,
+		globals:     i.globals,
+		types:       i.types,
+		converters:  i.converters,
+		heap:        i.heap,
+		addressed:   i.addressed,
+		out:         out,
+		errOut:      errOut,
+		raiseErrors: i.raiseErrors,
+		timeout:     i.timeout,
+	}
 }
 
 // Evaluate is a set of rules dictating how the tokens will be interpreted.
 func (i *interpreter) Evaluate(s statement) error {
+	ctx, cancel := i.statementContext()
+	defer cancel()
+	i.ctx = ctx
+	defer func() { i.ctx = nil }()
 	obj, err := i.evaluateStatement(s)
 	if err != nil {
 		return err
 	}
-	spew.Dump(obj)
+	spew.Fdump(i.out, obj)
 	return nil
 }
 
-// Evaluate is a set of rules dictating how the tokens will be interpreted.
-func (i *interpreter) evaluateStatement(s statement) (interface{}, error) {
-	ps, err := i.prepareStatement(s)
+// statementContext returns a context for evaluating a statement with, which is cancelled once the timeout
+// passes, or when an interrupt comes in if the interpreter is interruptible. Without either, it's never
+// cancelled.
+func (i *interpreter) statementContext() (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	switch {
+	case i.timeout > 0:
+		ctx, cancel = context.WithTimeout(context.Background(), i.timeout)
+	case i.interruptible:
+		ctx, cancel = context.WithCancel(context.Background())
+	default:
+		// Nothing can cancel it, which saves calls from having to wait on it
+		return context.Background(), func() {}
+	}
+	if !i.interruptible {
+		return ctx, cancel
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupts)
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// statementCtx returns the context of the statement being evaluated
+func (i *interpreter) statementCtx() context.Context {
+	if i.ctx == nil {
+		return context.Background()
+	}
+	return i.ctx
+}
+
+// evaluateStatement parses the statement into an expression tree, and walks it to produce a result
+func (i *interpreter) evaluateStatement(s statement) (obj interface{}, err error) {
+	n, err := parseStatement(s)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		// Nothing to do for an empty statement
+		return nil, nil
+	}
+	// Anything more specific should have already been caught, but never let a panic take down the session
+	defer func() {
+		if r := recover(); r != nil {
+			obj, err = nil, panicError(n, r)
+		}
+	}()
+	v, err := i.evaluate(n)
 	if err != nil {
 		return nil, err
 	}
-	switch ps.t {
-	case INSPECT:
-		obj, err := i.lookupVariable(ps.lhs)
+	return valueToInterface(n, v)
+}
+
+// evaluate walks a single node of the expression tree, returning the value it results in
+func (i *interpreter) evaluate(n node) (reflect.Value, error) {
+	switch n := n.(type) {
+	case *identNode:
+		obj, err := i.lookupVariable(n)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
+		}
+		if i.addressed[n.name] {
+			// The heap holds a pointer to the variable, which makes it addressable
+			return reflect.ValueOf(obj).Elem(), nil
 		}
-		return obj, nil
-	case LOOKUP:
-		// Pass from the 4th token on (LPAREN <-> RPAREN : EOF))
-		// get back arguments for dynamic finder
-		stype, id, err := statementToFindArgs(ps.lhs[1:])
+		return reflect.ValueOf(obj), nil
+	case *literalNode:
+		obj, err := literalToValue(n)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
 		}
-		obj, err := i.find(stype, id)
+		return reflect.ValueOf(obj), nil
+	case *findNode:
+		return i.find(n)
+	case *newNode:
+		t, err := i.resolveType(n.typ, n.pos)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
 		}
-		return obj, nil
-	case LITERAL:
-		// Like a variable, but just a literal value
-		var obj interface{}
-		var err error
-		switch s[0].token {
-		case BOOL:
-			obj, err = stringToBool(s[0].text)
-		case RUNE:
-			obj, err = stringToRune(s[0].text)
-		case STRING:
-			obj, err = stringToString(s[0].text)
-		case INT:
-			obj, err = stringToInt(s[0].text)
-		case FLOAT:
-			obj, err = stringToFloat64(s[0].text)
+		return reflect.New(t), nil
+	case *compositeNode:
+		if n.typ == nil {
+			return reflect.Value{}, fmt.Errorf("Error: Cannot tell what type %s is, it needs a type in front of it", n)
 		}
+		t, err := i.resolveType(n.typ, n.pos)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
 		}
-		return obj, nil
-	case METHODCALL:
-		chain, args := statementToInvocationChainAndParams(ps.lhs)
-		// It's a method invocation
-		// The first word in the statement is the variable
-		// the next N are the property chains
-		// the args are the things to invoke on the method
-		obj, err := i.callMethodChain(chain, args)
+		return i.evaluateComposite(n, t)
+	case *listNode:
+		// Without anything to say otherwise, a list can hold anything
+		return i.buildSlice(n, anySliceType, n.elems)
+	case *fieldNode, *indexNode, *sliceNode:
+		return i.crawlPropertyChain(n)
+	case *binaryNode:
+		return i.evaluateBinary(n)
+	case *unaryNode:
+		return i.evaluateUnary(n)
+	case *callNode:
+		r, err := i.callResults(n)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
 		}
-		return obj, nil
-	case PROPERTYCALL:
-		chain, _ := statementToInvocationChainAndParams(ps.lhs)
-		// It's a property invocation
-		// The first word in the statement is the variable
-		// the next N are the property chains
-		obj, err := i.callPropertyChain(chain)
+		// A single result stands on its own, and several come back as a list
+		switch len(r) {
+		case 0:
+			return reflect.Value{}, nil
+		case 1:
+			return r[0], nil
+		}
+		return resultList(n, r)
+	case *assignNode:
+		rhs, err := i.evaluate(n.rhs)
 		if err != nil {
-			return nil, err
+			return reflect.Value{}, err
 		}
-		return obj, nil
-	case ASSIGNMENT:
-		// This is the remaining case to port over
-		// it's still in the lookup branch below
-		// we need to do some recursion here in the new world order
-		// send LHS and RHS down the Evaluate tree, and get back their objects
-		// then assign RHS to LHS.
-		// This requires re-designing Evaluate to return an interface{}, error
-		// likely - break this into 2 methods. A wrapper not to be recursed by the
-		// caller, and the actual method, which returns an object and an error, for
-		// the purposes of being able to be called recursively?
-		lhs, err := i.evaluateStatement(ps.lhs)
-		if err != nil && !strings.Contains(err.Error(), "Unknown variable") {
-			return nil, err
+		if err := i.assign(n.lhs, n.rhs, rhs); err != nil {
+			return reflect.Value{}, err
 		}
+		return rhs, nil
+	case *multiAssignNode:
+		return i.evaluateMultiAssign(n)
+	}
+	return reflect.Value{}, fmt.Errorf("Error: \"%s\" is not a valid statement", n)
+}
 
-		rhs, err := i.evaluateStatement(ps.rhs)
+// assign stores v, which is the result of evaluating rhs, into lhs. A variable named _ throws the value away.
+func (i *interpreter) assign(lhs node, rhs node, v reflect.Value) error {
+	switch lhs := lhs.(type) {
+	case *identNode:
+		if lhs.name == "_" {
+			return nil
+		}
+		obj, err := valueToInterface(rhs, v)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		i.storeInHeap(ps.lhs[0].text, rhs)
-		lhs = rhs
-		return lhs, nil
-	case INVALID:
-	default:
-		return nil, fmt.Errorf("Error: \"%v\" is not a valid statement", ps.fullStatement)
+		return i.storeInHeap(lhs.name, obj)
+	case *fieldNode, *indexNode:
+		return i.assignProperty(lhs, rhs, v)
+	case *unaryNode:
+		if lhs.op.token == MULT {
+			return i.assignPointer(lhs, rhs, v)
+		}
+	}
+	return fmt.Errorf("Error: Cannot assign to %s, only variables, properties, indexes, and pointers can be assigned to", lhs)
+}
+
+// evaluateMultiAssign calls the method on the right, and stores each of its results into the matching
+// node on the left, like v, err = o.Load(5)
+func (i *interpreter) evaluateMultiAssign(n *multiAssignNode) (reflect.Value, error) {
+	c, ok := n.rhs.(*callNode)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("Error: Cannot assign %s to %d variables, only a method call returns more than one value", n.rhs, len(n.lhs))
+	}
+	r, err := i.callResults(c)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if len(r) != len(n.lhs) {
+		return reflect.Value{}, fmt.Errorf("Error: Cannot assign %d values to %d variables, in %s", len(r), len(n.lhs), n)
+	}
+	for j, lhs := range n.lhs {
+		if err := i.assign(lhs, c, r[j]); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return resultList(c, r)
+}
+
+// resultList gathers up the results of the call n, so they can be shown together
+func resultList(n node, r []reflect.Value) (reflect.Value, error) {
+	results := make([]interface{}, len(r))
+	for j, rv := range r {
+		obj, err := valueToInterface(n, rv)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		results[j] = obj
+	}
+	return reflect.ValueOf(results), nil
+}
+
+// evaluateComposite builds a new t out of the composite literal n. Structs have each of the named fields set,
+// slices get each of the elements, and maps each of the keys.
+func (i *interpreter) evaluateComposite(n *compositeNode, t reflect.Type) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Struct:
+		return i.buildStruct(n, t)
+	case reflect.Slice:
+		values := make([]node, len(n.elems))
+		for j, e := range n.elems {
+			if e.key != nil {
+				return reflect.Value{}, fmt.Errorf("Error: The elements of %s can't have keys", n)
+			}
+			values[j] = e.value
+		}
+		return i.buildSlice(n, t, values)
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(t, len(n.elems))
+		for _, e := range n.elems {
+			if e.key == nil {
+				return reflect.Value{}, fmt.Errorf("Error: Every element of %s needs a key, like %s{key: value}", n, t)
+			}
+			key, err := i.convertArgument(e.key, t.Key(), "key in "+n.String())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value, err := i.convertArgument(e.value, t.Elem(), "value for "+e.key.String())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			m.SetMapIndex(key, value)
+		}
+		return m, nil
+	}
+	return reflect.Value{}, fmt.Errorf("Error: Cannot build %s, a %s can't be built from a composite literal", n, t)
+}
+
+// buildStruct builds a new struct of type t, setting each of the fields named in n
+func (i *interpreter) buildStruct(n *compositeNode, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	set := make(map[string]bool)
+	for _, e := range n.elems {
+		key, ok := e.key.(*identNode)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("Error: Every value in %s needs a field name, like %s{Name: value}", n, t.Name())
+		}
+		field, ok := t.FieldByName(key.name)
+		if !ok || len(field.Index) > 1 {
+			return reflect.Value{}, fmt.Errorf("Error: %s has no field %s", t.Name(), key.name)
+		} else if field.PkgPath != "" {
+			return reflect.Value{}, fmt.Errorf("Error: Cannot set %s.%s, it is unexported", t.Name(), key.name)
+		} else if set[key.name] {
+			return reflect.Value{}, fmt.Errorf("Error: %s is set more than once in %s", key.name, n)
+		}
+		set[key.name] = true
+		fv, err := i.convertArgument(e.value, field.Type, "value for "+t.Name()+"."+key.name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.FieldByIndex(field.Index).Set(fv)
+	}
+	return v, nil
+}
+
+// buildSlice builds a new slice of type t, out of each of the values, which came from n
+func (i *interpreter) buildSlice(n node, t reflect.Type, values []node) (reflect.Value, error) {
+	v := reflect.MakeSlice(t, 0, len(values))
+	for j, value := range values {
+		ev, err := i.convertArgument(value, t.Elem(), fmt.Sprintf("element %d of %s", j+1, n))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v = reflect.Append(v, ev)
 	}
-	return nil, nil
+	return v, nil
 }
 
-func (i *interpreter) callMethodChain(chain statement, args statement) ([]interface{}, error) {
+// evaluateOperand evaluates a node whose result is used as a single value, such as the receiver of a
+// property, index, or method invocation, or an argument to a method.
+// Unlike in evaluate, a method call here is reduced to a single value, which lets calls be chained
+// together. See operandResult for how that value is picked.
+func (i *interpreter) evaluateOperand(n node) (reflect.Value, error) {
+	c, ok := n.(*callNode)
+	if !ok {
+		return i.evaluate(n)
+	}
+	r, err := i.callResults(c)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return operandResult(c, r)
+}
+
+// operandResult picks the value to carry on with out of the results of the call n. If the last result is
+// an error, and it isn't nil, the call failed and that's returned as a CallError. Otherwise it's the
+// first result that isn't the error.
+func operandResult(n *callNode, r []reflect.Value) (reflect.Value, error) {
+	if returnsError(r) {
+		if err := r[len(r)-1]; !err.IsNil() {
+			return reflect.Value{}, callError(n, err)
+		}
+		r = r[:len(r)-1]
+	}
+	if len(r) == 0 {
+		return reflect.Value{}, fmt.Errorf("Error: %s does not return a value", n)
+	}
+	return r[0], nil
+}
+
+// callResults calls n, and returns all of its results. When errors are being raised, a trailing error
+// result is taken off, and if it isn't nil, the call fails with it instead.
+func (i *interpreter) callResults(n *callNode) ([]reflect.Value, error) {
+	r, err := i.callMethodChain(n)
+	if err != nil || !i.raiseErrors || !returnsError(r) {
+		return r, err
+	}
+	if err := r[len(r)-1]; !err.IsNil() {
+		return nil, callError(n, err)
+	}
+	return r[:len(r)-1], nil
+}
+
+// returnsError reports if the last of a call's results is an error
+func returnsError(r []reflect.Value) bool {
+	return len(r) > 0 && r[len(r)-1].Type() == errorType
+}
+
+func callError(n *callNode, err reflect.Value) error {
+	return &CallError{Pos: n.pos, Call: n.String(), Err: err.Interface().(error)}
+}
+
+func (i *interpreter) callMethodChain(n *callNode) (results []reflect.Value, err error) {
 	// No crashing!
 	defer func() {
-		if err := recover(); err != nil {
-			fmt.Printf("Recovering from panic: %s\n", err)
+		if r := recover(); r != nil {
+			results, err = nil, panicError(n, r)
 		}
 	}()
-	var err error
-	max := len(chain)
+	if fn, ok := i.lookupFunc(n.fn); ok {
+		return i.call(n, fn, n.args, n.spread)
+	}
+	f, ok := n.fn.(*fieldNode)
+	if !ok {
+		return nil, fmt.Errorf("Error: %s is not a method or a registered function, and cannot be called", n.fn)
+	}
 	// Get the object to call the method on
-	// if max-1 is the last element, and that is the funcion,
-	var obj interface{}
-	var ok bool
-	if max > 2 {
-		// if max > 2, then the chain needs to be evaluated to get the object
-		// to call the method on. Otherwise, the 2nd fragment is the method, and
-		// it's called directly
-		obj, err = i.crawlPropertyChain(chain[:max-1])
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		obj, ok = i.heap[chain[0].text]
-		if !ok {
-			return nil, fmt.Errorf("Error: Unknown variable %s", chain[0].text)
+	v, err := i.evaluateOperand(f.target)
+	if err != nil {
+		return nil, err
+	}
+	// Unwrap anything that came out of an interface{}, but leave pointers alone
+	// so that their methods are still visible
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("Error: Cannot call %s on %s, it is nil", f.name, f.target)
+	}
+	m := v.MethodByName(f.name)
+	writeBack := false
+	var orig reflect.Value
+	if !m.IsValid() && v.Kind() != reflect.Ptr {
+		// Methods with a pointer receiver are called on the address of the value. If it isn't addressable,
+		// like a variable holding a value, the method gets a copy, which is stored back where it came from
+		// once the call is done, like calling the method in Go would change it. Read only variables, and the
+		// results of other calls, have nowhere to keep the changes, so they're left alone.
+		if !v.CanAddr() {
+			v = copyOf(v)
+			writeBack = i.canWriteBack(f.target)
+		} else if i.statementCtx().Done() != nil {
+			// The call could be abandoned, and keep running after the statement is cancelled, so it gets
+			// a copy too, which is only stored back if it finishes in time
+			orig, v = v, copyOf(v)
 		}
+		m = v.Addr().MethodByName(f.name)
+	}
+	if !m.IsValid() {
+		return nil, &MethodNotFoundError{Pos: f.pos, Receiver: f.target.String(), Type: v.Type().String(), Method: f.name}
+	}
+	results, err = i.call(n, m, n.args, n.spread)
+	if err != nil {
+		return results, err
+	}
+	if orig.IsValid() {
+		orig.Set(v)
+	} else if writeBack {
+		err = i.assign(f.target, f.target, v)
 	}
+	return results, err
+}
+
+// copyOf returns an addressable copy of v
+func copyOf(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	return cp.Elem()
+}
 
-	// Get the reflect value and look up the method
-	v := reflect.ValueOf(obj)
-	// Don't do this for methods.. but perhaps we need a ptr/nonptr fallback?
-	//if v.Kind() == reflect.Ptr {
-	//	v = v.Elem()
-	//}
-	mname := chain[max-1].text
-	m := v.MethodByName(mname)
-	mtype := m.Type()
+// canWriteBack reports if n is a variable, or a property or index reached from one, that can be assigned
+// the changes a pointer receiver method made to a copy of it
+func (i *interpreter) canWriteBack(n node) bool {
+	switch n.(type) {
+	case *identNode, *fieldNode, *indexNode:
+		root := rootVariable(n)
+		return root != nil && !i.isReadOnly(root.name)
+	}
+	return false
+}
 
-	inputArgs, err := i.statementToArgs(mtype, args)
+// call calls fn, which is a method, a registered function or a finder, with args for n. When spread is set,
+// the last argument is passed as the whole variadic parameter, like o.Tag(tags...). If fn takes a
+// context.Context first, it gets the statement's context, and the arguments fill in the rest.
+func (i *interpreter) call(n node, fn reflect.Value, args []node, spread bool) ([]reflect.Value, error) {
+	mtype := fn.Type()
+	skip := 0
+	if mtype.NumIn() > 0 && mtype.In(0) == contextType {
+		skip = 1
+	}
+	if err := checkArity(n, mtype, args, spread, skip); err != nil {
+		return nil, err
+	}
+	inputArgs, err := i.statementToArgs(mtype, args, spread, skip)
 	if err != nil {
 		return nil, err
 	}
+	if skip == 1 {
+		inputArgs = append([]reflect.Value{reflect.ValueOf(i.statementCtx())}, inputArgs...)
+	}
+	return i.await(n, func() []reflect.Value {
+		// Call the Method with the value args
+		if spread {
+			return fn.CallSlice(inputArgs)
+		}
+		return fn.Call(inputArgs)
+	})
+}
 
-	// Call the Method with the value args
-	r := m.Call(inputArgs)
-	results := make([]interface{}, len(r))
-	// Print all the results
-	for i, rv := range r {
-		fmt.Printf("%s[%d] : %v\n", mname, i, rv)
-		results[i] = rv.Interface()
+// await runs f, which calls into the integrating application, and returns what it returns. When the statement
+// can be cancelled, f runs on its own goroutine, so that we can stop waiting on it. If the statement is
+// cancelled first, f is abandoned, and left to finish on its own, with n blamed for the cancellation. Anything
+// f panics with comes back as a CallPanicError.
+func (i *interpreter) await(n node, f func() []reflect.Value) (results []reflect.Value, err error) {
+	ctx := i.statementCtx()
+	if ctx.Done() == nil {
+		// Nothing can cancel the statement, so just call it
+		defer func() {
+			if r := recover(); r != nil {
+				results, err = nil, panicError(n, r)
+			}
+		}()
+		return f(), nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &CancelledError{Pos: n.Pos(), Call: n.String(), Err: err}
+	}
+	type outcome struct {
+		results []reflect.Value
+		err     error
 	}
-	return results, nil
+	// Buffered, so an abandoned call can still finish without anyone waiting on it
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: panicError(n, r)}
+			}
+		}()
+		done <- outcome{results: f()}
+	}()
+	select {
+	case o := <-done:
+		return o.results, o.err
+	case <-ctx.Done():
+		return nil, &CancelledError{Pos: n.Pos(), Call: n.String(), Err: ctx.Err()}
+	}
+}
+
+// checkArity makes sure that n passes the right number of arguments to a function of type mtype, leaving
+// out the first skip parameters, which are filled in by the interpreter
+func checkArity(n node, mtype reflect.Type, args []node, spread bool, skip int) error {
+	want, got := mtype.NumIn()-skip, len(args)
+	switch {
+	case spread && !mtype.IsVariadic():
+		return fmt.Errorf("Error: Cannot use ... in %s, %s is not variadic", n, mtype)
+	case spread && got != want:
+		return fmt.Errorf("Error: Wrong number of arguments in %s, %s takes %d, with the last one spread", n, mtype, want)
+	case mtype.IsVariadic() && got < want-1:
+		return fmt.Errorf("Error: Not enough arguments in %s, %s takes at least %d", n, mtype, want-1)
+	case !mtype.IsVariadic() && got < want:
+		return fmt.Errorf("Error: Not enough arguments in %s, %s takes %d", n, mtype, want)
+	case !mtype.IsVariadic() && got > want:
+		return fmt.Errorf("Error: Too many arguments in %s, %s takes %d", n, mtype, want)
+	}
+	return nil
+}
+
+// lookupFunc returns the registered function n names, either on its own like now, or with a package
+// in front of it like services.Reindex, falling back to the built in functions
+func (i *interpreter) lookupFunc(n node) (reflect.Value, bool) {
+	var name string
+	switch n := n.(type) {
+	case *identNode:
+		name = n.name
+	case *fieldNode:
+		pkg, ok := n.target.(*identNode)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		name = pkg.name + "." + n.name
+	default:
+		return reflect.Value{}, false
+	}
+	if fn, ok := i.funcs[name]; ok {
+		return fn, true
+	}
+	return i.builtin(name)
 }
 
-func (i *interpreter) crawlPropertyChain(statement statement) (interface{}, error) {
+// crawlPropertyChain resolves a chain of property accesses and indexes down to the value at the end of it
+func (i *interpreter) crawlPropertyChain(n node) (v reflect.Value, err error) {
 	// No crashing!
 	defer func() {
-		if err := recover(); err != nil {
-			fmt.Printf("Recovering from panic: %s\n", err)
+		if r := recover(); r != nil {
+			v, err = reflect.Value{}, panicError(n, r)
 		}
 	}()
-	obj, ok := i.heap[statement[0].text]
-	if !ok {
-		return nil, fmt.Errorf("Error: Unknown variable %s", statement[0].text)
-	}
-	currentVal := reflect.ValueOf(obj)
-	parsingIndex := false
-	for _, f := range statement[1:] {
-		if f.token != EOF && f.token != PERIOD && f.token != RBRACK {
-			if f.token == LBRACK {
-				parsingIndex = true
-			} else if parsingIndex {
-				indexval, err := strconv.Atoi(f.text)
-				if err != nil {
-					return nil, fmt.Errorf("Error: Unable to use %s as an index value for %v. Original error: %s", f.text, currentVal, err.Error())
-				}
-				currentVal = currentVal.Index(indexval)
-				parsingIndex = false
-			} else {
-				// We're not dealing with an indexing operation, this is a straight invocation of a property
-				// Deref if we're dealing with a pointer
-				if currentVal.Kind() == reflect.Ptr {
-					currentVal = currentVal.Elem()
-				}
-				p := currentVal.FieldByName(f.text)
-				currentVal = p
+	switch n := n.(type) {
+	case *fieldNode:
+		currentVal, err := i.evaluateOperand(n.target)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// Deref if we're dealing with a pointer
+		if currentVal, err = indirect(n.target, currentVal); err != nil {
+			return reflect.Value{}, err
+		}
+		if currentVal.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("Error: %s is a %s, not a struct, and has no property %s", n.target, currentVal.Type(), n.name)
+		}
+		p := currentVal.FieldByName(n.name)
+		if !p.IsValid() {
+			return reflect.Value{}, fmt.Errorf("Error: %s has no property %s", n.target, n.name)
+		}
+		return p, nil
+	case *indexNode:
+		currentVal, err := i.evaluateOperand(n.target)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if currentVal, err = indirect(n.target, currentVal); err != nil {
+			return reflect.Value{}, err
+		}
+		if currentVal.Kind() == reflect.Map {
+			key, err := i.mapKey(n, currentVal)
+			if err != nil {
+				return reflect.Value{}, err
 			}
+			v := currentVal.MapIndex(key)
+			if !v.IsValid() {
+				return reflect.Value{}, &MissingKeyError{Pos: n.index.Pos(), Map: n.target.String(), Key: key.Interface()}
+			}
+			return v, nil
+		}
+		switch currentVal.Kind() {
+		case reflect.Slice, reflect.Array, reflect.String:
+		default:
+			return reflect.Value{}, fmt.Errorf("Error: %s is a %s, and cannot be indexed", n.target, currentVal.Type())
+		}
+		indexval, err := i.evaluateIndex(n.index, n.target, currentVal.Len())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if indexval < 0 || indexval >= currentVal.Len() {
+			return reflect.Value{}, fmt.Errorf("Error: Index %s is out of range for %s, which has length %d", n.index, n.target, currentVal.Len())
+		}
+		return currentVal.Index(indexval), nil
+	case *sliceNode:
+		currentVal, err := i.evaluateOperand(n.target)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if currentVal, err = indirect(n.target, currentVal); err != nil {
+			return reflect.Value{}, err
 		}
+		switch currentVal.Kind() {
+		case reflect.Slice, reflect.String:
+		case reflect.Array:
+			// Only addressable arrays can be sliced, so make a copy if we have to
+			if !currentVal.CanAddr() {
+				cp := reflect.New(currentVal.Type()).Elem()
+				cp.Set(currentVal)
+				currentVal = cp
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("Error: %s is a %s, and cannot be sliced", n.target, currentVal.Type())
+		}
+		low, high := 0, currentVal.Len()
+		if n.low != nil {
+			if low, err = i.evaluateIndex(n.low, n.target, currentVal.Len()); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		if n.high != nil {
+			if high, err = i.evaluateIndex(n.high, n.target, currentVal.Len()); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		if low < 0 || high > currentVal.Len() || low > high {
+			return reflect.Value{}, fmt.Errorf("Error: Slice bounds [%d:%d] are out of range for %s, which has length %d", low, high, n.target, currentVal.Len())
+		}
+		return currentVal.Slice(low, high), nil
 	}
+	return reflect.Value{}, fmt.Errorf("Error: %s is not a property chain", n)
+}
 
-	return currentVal.Interface(), nil
+// assignProperty sets the property, slice element, or map entry at the end of the chain lhs to v,
+// which is the result of evaluating rhs
+func (i *interpreter) assignProperty(lhs node, rhs node, v reflect.Value) error {
+	if root := i.valueRoot(lhs); root != nil {
+		if i.isReadOnly(root.name) {
+			return fmt.Errorf("Error: Cannot assign to %s, %s is read only", lhs, root)
+		}
+		// Make the variable addressable, so the assignment changes what it holds rather than a copy
+		i.addressVariable(root.name)
+	}
+	if idx, ok := lhs.(*indexNode); ok {
+		// Map entries can't be addressed, so they're set directly on the map
+		m, err := i.evaluateOperand(idx.target)
+		if err != nil {
+			return err
+		}
+		if m, err = indirect(idx.target, m); err != nil {
+			return err
+		}
+		if m.Kind() == reflect.Map {
+			if m.IsNil() {
+				return fmt.Errorf("Error: Cannot assign to %s, %s is a nil map", lhs, idx.target)
+			}
+			key, err := i.mapKey(idx, m)
+			if err != nil {
+				return err
+			}
+			val, err := assignableValue(rhs, v, m.Type().Elem())
+			if err != nil {
+				return fmt.Errorf("Error: Cannot assign to %s: %s", lhs, err.Error())
+			}
+			m.SetMapIndex(key, val)
+			return nil
+		}
+	}
+	target, err := i.crawlPropertyChain(lhs)
+	if err != nil {
+		return err
+	}
+	if !target.IsValid() || !target.CanAddr() {
+		return fmt.Errorf("Error: Cannot assign to %s, it is not addressable", lhs)
+	} else if !target.CanSet() {
+		return fmt.Errorf("Error: Cannot assign to %s, it is unexported", lhs)
+	}
+	val, err := assignableValue(rhs, v, target.Type())
+	if err != nil {
+		return fmt.Errorf("Error: Cannot assign to %s: %s", lhs, err.Error())
+	}
+	target.Set(val)
+	return nil
 }
 
-func (i *interpreter) callPropertyChain(statement statement) (interface{}, error) {
-	obj, err := i.crawlPropertyChain(statement)
+// assignPointer sets the value lhs points to, like *p = 5, to v, which is the result of evaluating rhs
+func (i *interpreter) assignPointer(lhs *unaryNode, rhs node, v reflect.Value) error {
+	p, err := i.evaluateOperator(lhs.operand)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	fmt.Printf("%+v\n", obj)
-	return obj, nil
+	if p.Kind() != reflect.Ptr {
+		return fmt.Errorf("Error: Cannot assign to %s, %s is a %s rather than a pointer", lhs, lhs.operand, typeName(p))
+	} else if p.IsNil() {
+		return fmt.Errorf("Error: Cannot assign to %s, %s is nil", lhs, lhs.operand)
+	}
+	val, err := assignableValue(rhs, v, p.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("Error: Cannot assign to %s: %s", lhs, err.Error())
+	}
+	p.Elem().Set(val)
+	return nil
 }
 
-func statementToInvocationChainAndParams(s statement) (statement, statement) {
-	chain := make(statement, 0)
-	args := make(statement, 0)
-	hitParen := false
-	for _, f := range s {
-		if f.token == LPAREN {
-			hitParen = true
-			args = append(args, f)
-		} else if !hitParen && f.token != PERIOD {
-			// We're still in the chain
-			chain = append(chain, f)
-		} else if hitParen {
-			// We're in the method params
-			args = append(args, f)
+// evaluateIndex evaluates n as an index into target, which has the given length. Negative indexes count
+// back from the end, so -1 is the last element.
+func (i *interpreter) evaluateIndex(n node, target node, length int) (int, error) {
+	v, err := i.evaluateOperator(n)
+	if err != nil {
+		return 0, err
+	}
+	var index int
+	switch classify(v) {
+	case intClass, uintClass:
+		index = int(asInt(v))
+	default:
+		return 0, fmt.Errorf("Error: Unable to use %s as an index value for %s, it is not an int", n, target)
+	}
+	if index < 0 {
+		index += length
+	}
+	return index, nil
+}
+
+// mapKey converts the index of n into a key for the map m
+func (i *interpreter) mapKey(n *indexNode, m reflect.Value) (reflect.Value, error) {
+	return i.convertArgument(n.index, m.Type().Key(), "key for "+n.target.String())
+}
+
+// rootVariable returns the variable at the base of a property chain, or nil if the chain
+// starts from something else, like the result of a method call
+func rootVariable(n node) *identNode {
+	for {
+		switch t := n.(type) {
+		case *identNode:
+			return t
+		case *fieldNode:
+			n = t.target
+		case *indexNode:
+			n = t.target
+		case *sliceNode:
+			n = t.target
+		default:
+			return nil
 		}
 	}
-	if hitParen {
-		return chain, args
+}
+
+// valueRoot returns the variable at the base of the property chain n, when it holds a value rather than
+// a pointer, so nothing reached through it is addressable. Otherwise, it returns nil.
+func (i *interpreter) valueRoot(n node) *identNode {
+	root := rootVariable(n)
+	if root == nil {
+		return nil
+	}
+	obj, ok := i.heap[root.name]
+	if !ok || obj == nil || reflect.TypeOf(obj).Kind() == reflect.Ptr {
+		return nil
 	}
-	return chain, nil
+	return root
 }
 
-func statementToFindArgs(s statement) (string, string, error) {
-	max := len(s)
-	if max == 6 && // Find statement should only have 7 fragements
-		s[0].token == LPAREN &&
-		s[max-1].token == EOF &&
-		s[max-2].token == RPAREN {
-		// Valid set of args so far
-		return s[1].text, s[3].text, nil
+// indirect unwraps interfaces and dereferences pointers, until it reaches a concrete value
+func indirect(n node, v reflect.Value) (reflect.Value, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("Error: %s is nil", n)
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("Error: %s is nil", n)
 	}
-	// TODO re-assemble statement for error message
-	return "", "", fmt.Errorf("Error: Invalid set of arguments for Find")
+	return v, nil
+}
+
+// valueToInterface gets the underlying object back out of a value produced by evaluating n
+func valueToInterface(n node, v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if !v.CanInterface() {
+		return nil, fmt.Errorf("Error: %s is unexported, and cannot be accessed", n)
+	}
+	return v.Interface(), nil
+}
+
+func literalToValue(n *literalNode) (interface{}, error) {
+	var c Converter
+	var t string
+	switch n.token {
+	case BOOL:
+		c, t = stringToBool, "bool"
+	case RUNE:
+		c, t = stringToRune, "rune"
+	case STRING:
+		c, t = stringToString, "string"
+	case INT:
+		c, t = stringToInt, "int"
+	case FLOAT:
+		c, t = stringToFloat64, "float64"
+	default:
+		return nil, fmt.Errorf("Error: %s is not a literal value", n)
+	}
+	v, err := c(n.text)
+	if err != nil {
+		// Like 9999999999999999999999, which is too big for an int
+		return nil, &ConversionError{Pos: n.pos, Value: n.text, Type: t, Err: err}
+	}
+	return v, nil
 }
 
 func cleanWhitespace(s statement) statement {
@@ -354,82 +907,456 @@ func cleanWhitespace(s statement) statement {
 
 // RegisterFinder is for registering one of your custom finders to look up your structs
 func (i *interpreter) RegisterFinder(name string, f Finder) {
-	i.finders[name] = f
+	i.finders[name] = finder{byID: func(_ context.Context, id string) (interface{}, error) {
+		return f(id)
+	}}
+}
+
+// RegisterContextFinder is for registering a finder that takes the statement's context
+func (i *interpreter) RegisterContextFinder(name string, f ContextFinder) {
+	i.finders[name] = finder{byID: f}
+}
+
+// RegisterFinderFunc is for registering any function as the finder for a type, which returns the value, and
+// optionally an error
+func (i *interpreter) RegisterFinderFunc(name string, fn interface{}) error {
+	v, err := finderFunc(name, fn, false)
+	if err != nil {
+		return err
+	}
+	i.finders[name] = finder{fn: v}
+	return nil
+}
+
+// RegisterMultiFinder is for registering a function that findAll uses to look up a slice of a type
+func (i *interpreter) RegisterMultiFinder(name string, fn interface{}) error {
+	v, err := finderFunc(name, fn, true)
+	if err != nil {
+		return err
+	}
+	i.allFinders[name] = finder{fn: v}
+	return nil
+}
+
+// finderFunc checks that fn can be used as a finder, which returns a value, or a slice of them when all is
+// set, followed by an optional error
+func finderFunc(name string, fn interface{}, all bool) (reflect.Value, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return reflect.Value{}, fmt.Errorf("Error: Cannot register a finder for %s, %T is not a function", name, fn)
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() == 0 || t.NumOut() > 2:
+		return reflect.Value{}, fmt.Errorf("Error: Cannot register a finder for %s, %s must return a value and optionally an error", name, t)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return reflect.Value{}, fmt.Errorf("Error: Cannot register a finder for %s, the last result of %s must be an error", name, t)
+	case all && t.Out(0).Kind() != reflect.Slice:
+		return reflect.Value{}, fmt.Errorf("Error: Cannot register a finder for %s, %s must return a slice", name, t)
+	}
+	return v, nil
+}
+
+// RegisterFunc is for registering a function that can be called directly in a session
+func (i *interpreter) RegisterFunc(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("Error: Cannot register %s, %T is not a function", name, fn)
+	}
+	i.funcs[name] = v
+	return nil
+}
+
+// RegisterType is for making a type available to sessions, by its name
+func (i *interpreter) RegisterType(sample interface{}) error {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		return fmt.Errorf("Error: Cannot register the type of nil")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return fmt.Errorf("Error: Cannot register %s, only named types can be registered", t)
+	}
+	if other, ok := i.types[t.Name()]; ok && other != t {
+		// Sessions only know types by their name, so two of them can't share one
+		return fmt.Errorf("Error: Cannot register %s.%s, %s.%s is already registered as %s", t.PkgPath(), t.Name(), other.PkgPath(), other.Name(), t.Name())
+	}
+	i.types[t.Name()] = t
+	return nil
+}
+
+// resolveType returns the type that spec describes, which was found at pos. Names are looked up in the
+// registered types first, and then Go's own.
+func (i *interpreter) resolveType(spec *typeSpec, pos Position) (reflect.Type, error) {
+	switch spec.kind {
+	case reflect.Slice, reflect.Ptr:
+		elem, err := i.resolveType(spec.elem, pos)
+		if err != nil {
+			return nil, err
+		}
+		if spec.kind == reflect.Ptr {
+			return reflect.PtrTo(elem), nil
+		}
+		return reflect.SliceOf(elem), nil
+	case reflect.Map:
+		key, err := i.resolveType(spec.key, pos)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := i.resolveType(spec.elem, pos)
+		if err != nil {
+			return nil, err
+		}
+		if !key.Comparable() {
+			return nil, fmt.Errorf("Error: Invalid map key type %s, it isn't comparable", key)
+		}
+		return reflect.MapOf(key, elem), nil
+	}
+	if t, ok := i.types[spec.name]; ok {
+		return t, nil
+	}
+	if t, ok := builtinTypes[spec.name]; ok {
+		return t, nil
+	}
+	return nil, &UnknownTypeError{Pos: pos, Name: spec.name}
 }
 
-// RegisterConverter is for registering one of your custom converters to convert cli arguments to typed values
+// RegisterConverter is for registering one of your custom converters to convert cli arguments to typed values.
+// See converterNames for the names a type's converter can be registered under.
 func (i *interpreter) RegisterConverter(name string, c Converter) {
 	i.converters[name] = c
 }
 
+// RegisterGlobal is for putting a variable in the heap of every session
+func (i *interpreter) RegisterGlobal(name string, v interface{}, readOnly bool) {
+	i.globals[name] = global{value: v, readOnly: readOnly}
+	i.heap[name] = v
+	delete(i.addressed, name)
+}
+
+// isReadOnly reports if the variable id is a global that sessions can't reassign
+func (i *interpreter) isReadOnly(id string) bool {
+	g, ok := i.globals[id]
+	return ok && g.readOnly
+}
+
 func (i *interpreter) storeInHeap(id string, obj interface{}) error {
+	if i.isReadOnly(id) {
+		return fmt.Errorf("Error: Cannot assign to %s, it is read only", id)
+	}
+	if i.addressed[id] {
+		// Anything pointing at the variable sees what's assigned to it, so long as it's the same type. Otherwise,
+		// it becomes a new variable, and they keep pointing at the old one.
+		p := reflect.ValueOf(i.heap[id])
+		if obj != nil && reflect.TypeOf(obj) == p.Type().Elem() {
+			p.Elem().Set(reflect.ValueOf(obj))
+			return nil
+		}
+		delete(i.addressed, id)
+	}
 	// Store record in i.instances
 	i.heap[id] = obj
 
 	return nil
 }
 
-func (i *interpreter) lookupVariable(s statement) (interface{}, error) {
-	f := s[0]
-	obj, ok := i.heap[f.text]
+// addressVariable moves the variable called name behind a pointer, which the heap holds onto instead of its
+// value, so that it's addressable from then on, like any variable in Go. Reading the variable still gives the
+// value, but &name points at the variable itself, and changes made through properties or pointer receiver
+// methods happen in place.
+func (i *interpreter) addressVariable(name string) {
+	obj := i.heap[name]
+	if i.addressed[name] || obj == nil {
+		return
+	}
+	p := reflect.New(reflect.TypeOf(obj))
+	p.Elem().Set(reflect.ValueOf(obj))
+	i.heap[name] = p.Interface()
+	i.addressed[name] = true
+}
+
+// heapValue returns what the variable called name holds
+func (i *interpreter) heapValue(name string) interface{} {
+	if i.addressed[name] {
+		return reflect.ValueOf(i.heap[name]).Elem().Interface()
+	}
+	return i.heap[name]
+}
+
+func (i *interpreter) lookupVariable(n *identNode) (interface{}, error) {
+	obj, ok := i.heap[n.name]
 	if !ok {
-		return nil, fmt.Errorf("Error: %s is not a known variable", f.text)
+		return nil, &UnknownVariableError{Pos: n.pos, Name: n.name}
 	}
 	return obj, nil
 }
 
 // find will find things. It is basically a replacement, all purpose object constructor/retriever
-func (i *interpreter) find(stype string, id string) (interface{}, error) {
-	var obj interface{}
-	var err error
-	f := i.finders[stype]
-	if f == nil {
-		return nil, fmt.Errorf("No lookup method found for type %s", stype)
-	}
-	if obj, err = f(id); err != nil {
-		return nil, err
+func (i *interpreter) find(n *findNode) (reflect.Value, error) {
+	table := i.finders
+	if n.all {
+		table = i.allFinders
 	}
-	return obj, nil
+	f, ok := table[n.stype]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("No lookup method found for type %s", n.stype)
+	}
+	if f.byID != nil {
+		return i.findByID(n, f.byID)
+	}
+	results, err := i.call(n, f.fn, n.args, n.spread)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if len(results) == 2 && !results[1].IsNil() {
+		return reflect.Value{}, results[1].Interface().(error)
+	}
+	v := results[0]
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+// findByID calls one of the original finders, which always take a single id as a string
+func (i *interpreter) findByID(n *findNode, f ContextFinder) (reflect.Value, error) {
+	if len(n.args) != 1 || n.spread {
+		return reflect.Value{}, fmt.Errorf("Error: Wrong number of arguments in %s, the finder for %s takes a single id", n, n.stype)
+	}
+	id, err := i.evaluate(n.args[0])
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	idobj, err := valueToInterface(n.args[0], id)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ctx := i.statementCtx()
+	results, err := i.await(n, func() []reflect.Value {
+		obj, err := f(ctx, fmt.Sprint(idobj))
+		return []reflect.Value{reflect.ValueOf(obj), reflect.ValueOf(&err).Elem()}
+	})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if !results[1].IsNil() {
+		return reflect.Value{}, results[1].Interface().(error)
+	}
+	return results[0], nil
 }
 
-func (i *interpreter) statementToArgs(mtype reflect.Type, s statement) ([]reflect.Value, error) {
+// statementToArgs converts the arguments in s for a function of type mtype, starting from its parameter
+// numbered skip
+func (i *interpreter) statementToArgs(mtype reflect.Type, s []node, spread bool, skip int) (args []reflect.Value, err error) {
+	// The argument currently being worked on, to blame if anything panics
+	var arg node
 	// No crashing
 	defer func() {
-		if err := recover(); err != nil {
-			fmt.Printf("Recovering from panic: %s\n", err)
+		if r := recover(); r != nil && arg != nil {
+			args, err = nil, panicError(arg, r)
+		} else if r != nil {
+			panic(r)
 		}
 	}()
-	args := make([]reflect.Value, 0)
-	// statement should be of the format LPAREN [WORD WORD COMMA] ... RPAREN EOF
-	max := len(s)
-	if max == 3 {
-		// method with no params, return early
-		return args, nil
-	}
-	wordCount := 0
-	// TODO this feels like a super hacky way to do this. Improve it?
-	for _, currentfrag := range s {
-		if isValueToken(currentfrag.token) {
-			// hit a comma, reset
-			// Get the type of the argument
-			tparts := strings.Split(mtype.In(wordCount).String(), ".")
-			atype := tparts[len(tparts)-1] // Get whatever is at the final element of the split
-			var c Converter
-			var ok bool
-			if c, ok = i.converters[atype]; !ok {
-				return nil, fmt.Errorf("No converter found for type: %s", atype)
+	args = make([]reflect.Value, 0)
+	for wordCount := range s {
+		arg = s[wordCount]
+		t := argumentType(mtype, wordCount+skip)
+		if spread && wordCount == len(s)-1 {
+			// A spread argument is the whole slice, rather than one of its elements
+			t = mtype.In(wordCount + skip)
+		}
+		av, err := i.convertArgument(arg, t, fmt.Sprintf("argument %d", wordCount+1))
+		if err != nil {
+			return nil, err
+		}
+		// Add to the our list to return
+		args = append(args, av)
+	}
+	return args, nil
+}
+
+// buildElided builds the composite literal n, which had its type left out, as a t. Like in Go, a pointer
+// type gets a pointer to a new value.
+func (i *interpreter) buildElided(n *compositeNode, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		v, err := i.evaluateComposite(n, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+		return p, nil
+	}
+	return i.evaluateComposite(n, t)
+}
+
+// argumentType returns the type of the kth argument to a function of type mtype. Everything from the last
+// parameter of a variadic function onward is one of its elements.
+func argumentType(mtype reflect.Type, k int) reflect.Type {
+	if last := mtype.NumIn() - 1; mtype.IsVariadic() && k >= last {
+		return mtype.In(last).Elem()
+	}
+	return mtype.In(k)
+}
+
+// convertArgument produces a t out of n, for passing as an argument or using as a map key, which is
+// described by what in any errors. Literals are run through the converter registered for t, if there is
+// one. Everything else is evaluated, and has to be assignable to t on its own.
+func (i *interpreter) convertArgument(n node, t reflect.Type, what string) (reflect.Value, error) {
+	switch n := n.(type) {
+	case *listNode:
+		if t.Kind() == reflect.Slice {
+			return i.buildSlice(n, t, n.elems)
+		}
+	case *compositeNode:
+		if n.typ == nil {
+			return i.buildElided(n, t)
+		}
+	case *unaryNode:
+		if lit, ok := n.operand.(*literalNode); ok && n.op.token == ADDR && t.Kind() == reflect.Ptr {
+			// The address of a literal points at it converted to whatever the pointer is to, like &50 for a *int8
+			v, err := i.convertArgument(lit, t.Elem(), what)
+			if err != nil {
+				return reflect.Value{}, err
 			}
-			// Convert, error on not found
-			iv, err := c(currentfrag.text)
+			p := reflect.New(t.Elem())
+			p.Elem().Set(v)
+			return p, nil
+		}
+	}
+	var v reflect.Value
+	var convErr error
+	lit, isLiteral := n.(*literalNode)
+	if isLiteral {
+		// Literals go through a converter, if one is registered for the type, or can be worked out from it
+		if c, name, ok := i.converterFor(t); ok {
+			iv, err := c(lit.text)
 			if err != nil {
-				return nil, fmt.Errorf("Error converting %s %s: %s", currentfrag.text, atype, err.Error())
+				return reflect.Value{}, &ConversionError{Pos: n.Pos(), Value: lit.text, Type: name, Err: err}
+			}
+			v = reflect.ValueOf(iv)
+		} else if c := i.derivedConverter(t); c != nil {
+			// Unlike a registered converter, it's fine for this one to fail, since the literal might fit as it is.
+			// A number that's out of range never will, though.
+			iv, err := c(lit.text)
+			if errors.Is(err, strconv.ErrRange) {
+				return reflect.Value{}, &ConversionError{Pos: n.Pos(), Value: lit.text, Type: t.String(), Err: err}
+			} else if err == nil {
+				v = reflect.ValueOf(iv)
+			} else {
+				convErr = err
 			}
-			// Add to the our list to return
-			args = append(args, reflect.ValueOf(iv))
-			wordCount++ // Could just take len of args over and over but eh
 		}
 	}
-	return args, nil
+	if !v.IsValid() {
+		// Anything else is evaluated as an expression, and has to fit the type on its own
+		var err error
+		if v, err = i.evaluateOperand(n); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	av, err := assignableValue(n, v, t)
+	if err != nil && isLiteral && convErr != nil {
+		return reflect.Value{}, &ConversionError{Pos: n.Pos(), Value: lit.text, Type: t.String(), Err: convErr}
+	} else if err != nil && isLiteral {
+		// The literal didn't fit on its own, and there was nothing to convert it
+		return reflect.Value{}, &NoConverterError{Pos: n.Pos(), Type: t.String()}
+	} else if err != nil {
+		return reflect.Value{}, fmt.Errorf("Error: Invalid %s: %s", what, err.Error())
+	}
+	return av, nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var anySliceType = reflect.TypeOf([]interface{}{})
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// builtinTypes are the types that can be referred to by name without being registered
+var builtinTypes = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"string":  reflect.TypeOf(""),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"byte":    reflect.TypeOf(byte(0)),
+	"rune":    reflect.TypeOf(rune(0)),
+	"error":   errorType,
+	"any":     anySliceType.Elem(),
+}
+
+// assignableValue checks that v, the result of evaluating n, can be used as a t. This follows Go's
+// assignability rules, with the addition of allowing conversions between numeric types, so long as the
+// number fits, and between named types that share an underlying kind, the same as you'd get with an
+// untyped constant.
+func assignableValue(n node, v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	// Unwrap anything that came out of an interface{}, so we check the real type
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		// nil can be used for anything that can be nil
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use %s (nil) as type %s", n, t)
+	}
+	if !v.CanInterface() {
+		return reflect.Value{}, fmt.Errorf("%s is unexported, and cannot be accessed", n)
+	}
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if isNumericKind(v.Kind()) && isNumericKind(t.Kind()) {
+		// Allow ints to widen into floats, but not floats to silently truncate into ints, or numbers to
+		// wrap around when they don't fit
+		if overflows(v, t) {
+			return reflect.Value{}, fmt.Errorf("cannot use %s (%v) as type %s, it is out of range", n, v.Interface(), t)
+		} else if !(isFloatKind(v.Kind()) && !isFloatKind(t.Kind())) {
+			return v.Convert(t), nil
+		}
+	} else if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s (type %s) as type %s", n, v.Type(), t)
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// overflows reports if the number in v doesn't fit in a t, like 300 in an int8, or -1 in a uint
+func overflows(v reflect.Value, t reflect.Type) bool {
+	target := reflect.New(t).Elem()
+	switch from, to := classify(v), classify(target); {
+	case from == intClass && to == intClass:
+		return target.OverflowInt(v.Int())
+	case from == intClass && to == uintClass:
+		return v.Int() < 0 || target.OverflowUint(uint64(v.Int()))
+	case from == uintClass && to == intClass:
+		return v.Uint() > math.MaxInt64 || target.OverflowInt(int64(v.Uint()))
+	case from == uintClass && to == uintClass:
+		return target.OverflowUint(v.Uint())
+	case from == floatClass && to == floatClass:
+		return target.OverflowFloat(v.Float())
+	}
+	return false
 }
 
 func isValueToken(t Token) bool {
@@ -1,8 +1,14 @@
 package instructor
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkInputHelp(b *testing.B) {
@@ -13,3 +19,843 @@ func BenchmarkInputHelp(b *testing.B) {
 		i.Evaluate([]fragment{f})
 	}
 }
+
+func TestAssignableValue(t *testing.T) {
+	n := &identNode{name: "x"}
+	var nilRecord *testRecord
+	cases := []struct {
+		v  interface{}
+		t  reflect.Type
+		ok bool
+	}{
+		{v: 5, t: reflect.TypeOf(int64(0)), ok: true},
+		{v: 5, t: reflect.TypeOf(float64(0)), ok: true},
+		{v: 5.5, t: reflect.TypeOf(0), ok: false},
+		{v: "x", t: reflect.TypeOf(0), ok: false},
+		{v: 5, t: reflect.TypeOf(""), ok: false},
+		{v: nil, t: reflect.TypeOf(nilRecord), ok: true},
+		{v: nil, t: reflect.TypeOf(0), ok: false},
+		{v: &Order{}, t: reflect.TypeOf(&Order{}), ok: true},
+		{v: Order{}, t: reflect.TypeOf(&Order{}), ok: false},
+		{v: 300, t: reflect.TypeOf(int8(0)), ok: false},
+		{v: 127, t: reflect.TypeOf(int8(0)), ok: true},
+		{v: int64(-129), t: reflect.TypeOf(int8(0)), ok: false},
+		{v: -1, t: reflect.TypeOf(uint(0)), ok: false},
+		{v: 5, t: reflect.TypeOf(uint8(0)), ok: true},
+		{v: uint64(1 << 63), t: reflect.TypeOf(int64(0)), ok: false},
+		{v: uint(256), t: reflect.TypeOf(uint8(0)), ok: false},
+		{v: 1e300, t: reflect.TypeOf(float32(0)), ok: false},
+	}
+	for _, c := range cases {
+		v, err := assignableValue(n, reflect.ValueOf(c.v), c.t)
+		if c.ok && err != nil {
+			t.Errorf("%T to %s: unexpected error %s", c.v, c.t, err)
+		} else if !c.ok && err == nil {
+			t.Errorf("%T to %s: expected an error", c.v, c.t)
+		} else if c.ok && v.Type() != c.t {
+			t.Errorf("%T to %s: got %s", c.v, c.t, v.Type())
+		}
+	}
+}
+
+type settable struct {
+	Name     string
+	Items    []int
+	Settings map[string]int
+	Child    *settable
+	hidden   int
+}
+
+func evalString(i *interpreter, s string) (interface{}, error) {
+	return i.evaluateStatement(lexStatement(s))
+}
+
+// evalCase is a statement, and the result evaluating it should give back. A nil result means the statement
+// only has to succeed.
+type evalCase struct {
+	statement string
+	result    interface{}
+}
+
+// checkResults evaluates each of the cases in order, and checks that they give back what they should
+func checkResults(t *testing.T, i *interpreter, cases []evalCase) {
+	t.Helper()
+	for _, c := range cases {
+		r, err := evalString(i, c.statement)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.statement, err)
+		} else if c.result != nil && !reflect.DeepEqual(r, c.result) {
+			t.Errorf("%s: got %#v, expected %#v", c.statement, r, c.result)
+		}
+	}
+}
+
+// checkFailures evaluates each of the statements, and checks that they fail
+func checkFailures(t *testing.T, i *interpreter, statements []string) {
+	t.Helper()
+	for _, s := range statements {
+		if _, err := evalString(i, s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("s", settable{Items: []int{1, 2, 3}, Settings: map[string]int{}, Child: &settable{}})
+	for _, s := range []string{
+		"s.Name = \"bob\"",
+		"s.Items[2] = 30",
+		"s.Settings[\"timeout\"] = 5",
+		"s.Child.Name = s.Name",
+		"p = s.Child",
+		"p.Items = s.Items",
+	} {
+		if _, err := evalString(i, s); err != nil {
+			t.Errorf("%s: unexpected error %s", s, err)
+		}
+	}
+	v := i.heapValue("s").(settable)
+	if v.Name != "bob" || v.Items[2] != 30 || v.Settings["timeout"] != 5 || v.Child.Name != "bob" || len(v.Child.Items) != 3 {
+		t.Errorf("Assignments were not applied: %+v %+v", v, v.Child)
+	}
+
+	checkFailures(t, i, []string{
+		"s.hidden = 5",
+		"s.Name[0] = 'x'",
+		"s.Name = 5",
+		"s.Missing = 5",
+		"s.Items[5] = 1",
+		"s.Child.Settings[\"x\"] = 1",
+	})
+}
+
+type userID string
+
+type cache struct {
+	Settings map[string]int
+	Entries  map[userID]*Order
+	ByFloop  map[Flooper]string
+	Names    map[int]string
+}
+
+func TestMapIndexing(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterConverter("Flooper", convertFloop)
+	i.storeInHeap("c", &cache{
+		Settings: map[string]int{"timeout": 30, "retries": 0},
+		Entries:  map[userID]*Order{"u1": {ID: "xxx"}},
+		ByFloop:  map[Flooper]string{{Floops: 5}: "five"},
+		Names:    map[int]string{7: "seven"},
+	})
+	i.storeInHeap("id", userID("u1"))
+	checkResults(t, i, []evalCase{
+		{"c.Settings[\"timeout\"]", 30},
+		{"c.Settings[\"retries\"]", 0},
+		{"c.Entries[\"u1\"].ID", "xxx"},
+		{"c.Entries[id].ID", "xxx"},
+		{"c.ByFloop[`{\"floops\": 5}`]", "five"},
+		{"c.Names[3 + 4]", "seven"},
+	})
+
+	var missing *MissingKeyError
+	if _, err := evalString(i, "c.Settings[\"nope\"]"); !errors.As(err, &missing) || missing.Key != "nope" {
+		t.Errorf("Expected a MissingKeyError, got %#v", err)
+	}
+	if _, err := evalString(i, "c.Names[\"seven\"]"); err == nil {
+		t.Errorf("Expected an error indexing with the wrong key type")
+	}
+}
+
+func TestSlicing(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("s", settable{Name: "instructor", Items: []int{0, 1, 2, 3, 4, 5}})
+	i.storeInHeap("a", [3]string{"x", "y", "z"})
+	checkResults(t, i, []evalCase{
+		{"s.Items[-1]", 5},
+		{"s.Items[-6]", 0},
+		{"s.Items[2:5]", []int{2, 3, 4}},
+		{"s.Items[:2]", []int{0, 1}},
+		{"s.Items[4:]", []int{4, 5}},
+		{"s.Items[-2:]", []int{4, 5}},
+		{"s.Items[:]", []int{0, 1, 2, 3, 4, 5}},
+		{"s.Items[1:3][1]", 2},
+		{"s.Name[:5]", "instr"},
+		{"a[1:]", []string{"y", "z"}},
+		{"s.Items[-1] = 50", 50},
+	})
+	if v := i.heapValue("s").(settable); v.Items[5] != 50 {
+		t.Errorf("Expected assigning to a negative index to set the last element, got %v", v.Items)
+	}
+
+	checkFailures(t, i, []string{
+		"s.Items[-7]",
+		"s.Items[4:2]",
+		"s.Items[:10]",
+		"s.Items[\"a\":]",
+		"s.Child[1:]",
+		"s.Items[1:2] = s.Items",
+	})
+}
+
+// directory is a fixture for chaining calls, with methods that hand back other objects, some with errors
+type directory struct {
+	Orders []*Order
+}
+
+func (d *directory) Users() *directory {
+	return d
+}
+
+func (d *directory) First() (*Order, error) {
+	if len(d.Orders) == 0 {
+		return nil, errors.New("no orders")
+	}
+	return d.Orders[0], nil
+}
+
+func (d *directory) Child(n int) (*Order, error) {
+	if n < 0 || n >= len(d.Orders) {
+		return nil, fmt.Errorf("no order %d", n)
+	}
+	return d.Orders[n], nil
+}
+
+func (d *directory) All() []*Order {
+	return d.Orders
+}
+
+func (d *directory) Nothing() {}
+
+func TestCallChains(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("d", &directory{Orders: []*Order{{ID: "xxx", NumFloops: 10}, {ID: "yyy", NumFloops: 5}}})
+	i.storeInHeap("empty", &directory{})
+	checkResults(t, i, []evalCase{
+		{"d.Users().First().ID", "xxx"},
+		{"d.Users().Users().Child(1).CustomID(true)", "onum-yyy-5"},
+		{"d.All()[1].ID", "yyy"},
+		{"d.All()[-1].CustomID(false)", "onum-yyy"},
+		{"d.Child(0).NumFloops + d.Child(1).NumFloops", 15},
+		{"d.Child(d.Child(1).NumFloops - 5).ID", "xxx"},
+	})
+
+	var callErr *CallError
+	if _, err := evalString(i, "empty.First().ID"); !errors.As(err, &callErr) || callErr.Err.Error() != "no orders" {
+		t.Errorf("Expected a CallError from an intermediate call, got %#v", err)
+	} else if callErr.Pos.Column != 7 {
+		t.Errorf("Expected the CallError to point at First, got %s", callErr.Pos)
+	}
+	if _, err := evalString(i, "d.Child(5).ID"); !errors.As(err, &callErr) {
+		t.Errorf("Expected a CallError from an intermediate call, got %#v", err)
+	}
+	if _, err := evalString(i, "d.Nothing().ID"); err == nil {
+		t.Errorf("Expected an error chaining off a call with no results")
+	}
+}
+
+func (d *directory) Load(n int) (*Order, error) {
+	return d.Child(n)
+}
+
+func (d *directory) Pair() (string, int) {
+	return "pair", 2
+}
+
+func TestDestructuring(t *testing.T) {
+	i := newInterpreter()
+	d := &directory{Orders: []*Order{{ID: "xxx", NumFloops: 10}, {ID: "yyy", NumFloops: 5}}}
+	i.storeInHeap("d", d)
+	i.storeInHeap("s", &settable{Items: []int{1, 2, 3}})
+	for _, s := range []string{
+		"o = d.Child(1).CustomID(false)",
+		"v, err = d.Load(0)",
+		"missing, failed = d.Load(9)",
+		"s.Name, s.Items[0] = d.Pair()",
+		"_, n = d.Pair()",
+	} {
+		if _, err := evalString(i, s); err != nil {
+			t.Errorf("%s: unexpected error %s", s, err)
+		}
+	}
+	if i.heap["o"] != "onum-yyy" {
+		t.Errorf("Expected a single result to be unwrapped, got %#v", i.heap["o"])
+	}
+	if i.heap["v"] != d.Orders[0] || i.heap["err"] != nil {
+		t.Errorf("Expected v and err to be set from Load, got %#v and %#v", i.heap["v"], i.heap["err"])
+	}
+	if err, ok := i.heap["failed"].(error); !ok || err.Error() != "no order 9" {
+		t.Errorf("Expected the error from Load to be stored in failed, got %#v", i.heap["failed"])
+	}
+	if s := i.heap["s"].(*settable); s.Name != "pair" || s.Items[0] != 2 {
+		t.Errorf("Expected properties to be set from Pair, got %#v", s)
+	}
+	if _, ok := i.heap["_"]; ok || i.heap["n"] != 2 {
+		t.Errorf("Expected _ to be discarded, and n to be set, got %#v", i.heap)
+	}
+	if r, err := evalString(i, "d.Pair()"); err != nil || !reflect.DeepEqual(r, []interface{}{"pair", 2}) {
+		t.Errorf("Expected several results to come back as a list, got %#v, %v", r, err)
+	}
+	checkFailures(t, i, []string{
+		"a, b, c = d.Pair()",
+		"a, b = d.Orders",
+		"a, 5 = d.Pair()",
+	})
+
+	i.raiseErrors = true
+	var callErr *CallError
+	if _, err := evalString(i, "v = d.Load(9)"); !errors.As(err, &callErr) || callErr.Err.Error() != "no order 9" {
+		t.Errorf("Expected a CallError when raising errors, got %#v", err)
+	}
+	if r, err := evalString(i, "v = d.Load(1)"); err != nil || r != d.Orders[1] {
+		t.Errorf("Expected the nil error to be dropped when raising errors, got %#v, %v", r, err)
+	}
+}
+
+func TestRegisteredFuncs(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("d", &directory{Orders: []*Order{{ID: "xxx", NumFloops: 10}}})
+	reindexed := ""
+	funcs := map[string]interface{}{
+		"hash": func(s string) string { return "#" + s },
+		"now":  func() int { return 42 },
+		"sum": func(base float64, xs ...int) float64 {
+			for _, x := range xs {
+				base += float64(x)
+			}
+			return base
+		},
+		"services.Reindex": func(o *Order, force bool) error {
+			if !force {
+				return errors.New("not forced")
+			}
+			reindexed = o.ID
+			return nil
+		},
+	}
+	for name, fn := range funcs {
+		if err := i.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("%s: unexpected error registering %s", name, err)
+		}
+	}
+	checkResults(t, i, []evalCase{
+		{"hash(\"x\")", "#x"},
+		{"now() + 1", 43},
+		{"hash(d.Child(0).ID)", "#xxx"},
+		{"sum(0.5)", 0.5},
+		{"sum(1, 2, 3, now())", 48.0},
+		{"services.Reindex(d.Orders[0], true)", nil},
+	})
+	if reindexed != "xxx" {
+		t.Errorf("Expected services.Reindex to have been called, got %q", reindexed)
+	}
+	checkFailures(t, i, []string{
+		"hash(d)",
+		"sum(1, \"a\")",
+		"nope(1)",
+		"services.Nope()",
+	})
+	if err := i.RegisterFunc("bad", 5); err == nil {
+		t.Errorf("Expected an error registering something that isn't a function")
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	i := newInterpreter()
+	db := &settable{Name: "db"}
+	i.RegisterGlobal("db", db, true)
+	i.RegisterGlobal("config", settable{Name: "prod"}, true)
+	i.RegisterGlobal("limit", 10, false)
+	for _, s := range []string{
+		"db.Name = \"changed\"",
+		"limit = 20",
+		"x = db.Name + config.Name",
+	} {
+		if _, err := evalString(i, s); err != nil {
+			t.Errorf("%s: unexpected error %s", s, err)
+		}
+	}
+	if db.Name != "changed" || i.heap["limit"] != 20 || i.heap["x"] != "changedprod" {
+		t.Errorf("Expected globals to be usable like any other variable, got %#v", i.heap)
+	}
+	checkFailures(t, i, []string{
+		"db = 5",
+		"config.Name = \"dev\"",
+	})
+	if i.heap["db"] != db || i.heap["config"].(settable).Name != "prod" {
+		t.Errorf("Expected read only globals to be left alone, got %#v", i.heap)
+	}
+
+	// Every new session starts out with the globals, as they were registered
+	s := i.newSession(ioutil.Discard, ioutil.Discard)
+	if s.heap["db"] != db || s.heap["limit"] != 10 || len(s.heap) != 3 {
+		t.Errorf("Expected a new session to hold just the globals, got %#v", s.heap)
+	}
+}
+
+type profile struct {
+	Name    string
+	Age     int
+	Owner   *Order
+	Floop   Flooper
+	private bool
+}
+
+func TestTypes(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterConverter("Flooper", convertFloop)
+	i.storeInHeap("o", &Order{ID: "xxx"})
+	if err := i.RegisterType(&profile{}); err != nil {
+		t.Fatalf("Unexpected error registering a type %s", err)
+	}
+	r, err := evalString(i, "profile{Name: \"a\", Age: 1 + 2, Owner: o, Floop: `{\"floops\": 5}`}")
+	if err != nil {
+		t.Fatalf("Unexpected error building an profile %s", err)
+	}
+	expected := profile{Name: "a", Age: 3, Owner: i.heap["o"].(*Order), Floop: Flooper{Floops: 5}}
+	if r != expected {
+		t.Errorf("Got %#v, expected %#v", r, expected)
+	}
+	if r, err := evalString(i, "new(profile)"); err != nil || !reflect.DeepEqual(r, &profile{}) {
+		t.Errorf("Expected new to allocate an empty profile, got %#v, %v", r, err)
+	}
+	if r, err := evalString(i, "profile{Age: 5}.Age * 2"); err != nil || r != 10 {
+		t.Errorf("Expected to use a composite literal in an expression, got %#v, %v", r, err)
+	}
+
+	var unknown *UnknownTypeError
+	if _, err := evalString(i, "new(User)"); !errors.As(err, &unknown) || unknown.Name != "User" {
+		t.Errorf("Expected an UnknownTypeError, got %#v", err)
+	}
+	checkFailures(t, i, []string{
+		"profile{\"a\"}",
+		"profile{Nope: 1}",
+		"profile{private: true}",
+		"profile{Age: \"old\"}",
+		"profile{Age: 1, Age: 2}",
+		"User{}",
+	})
+	if err := i.RegisterType(struct{}{}); err == nil {
+		t.Errorf("Expected an error registering an unnamed type")
+	}
+	if err := i.RegisterType(profile{}); err != nil {
+		t.Errorf("Unexpected error registering the same type again %s", err)
+	}
+	type profile struct{ Other bool }
+	if err := i.RegisterType(profile{}); err == nil {
+		t.Errorf("Expected an error registering a different type with the same name")
+	}
+	if r, err := evalString(i, "profile{Age: 5}.Age"); err != nil || r != 5 {
+		t.Errorf("Expected the first profile type to stay registered, got %#v, %v", r, err)
+	}
+}
+
+func (d *directory) Tag(tags []string, counts map[string]int) string {
+	return fmt.Sprint(tags, counts)
+}
+
+func (d *directory) Sum(xs []float64) float64 {
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+func (d *directory) Floops(fs []Flooper) int {
+	total := 0
+	for _, f := range fs {
+		total += f.Floops
+	}
+	return total
+}
+
+func TestCollectionLiterals(t *testing.T) {
+	i := newInterpreter()
+	i.RegisterConverter("Flooper", convertFloop)
+	i.RegisterType(Order{})
+	i.storeInHeap("d", &directory{})
+	i.storeInHeap("n", 3)
+	checkResults(t, i, []evalCase{
+		{"[]string{\"a\", \"b\"}", []string{"a", "b"}},
+		{"[]int{}", []int{}},
+		{"map[string]int{\"a\": 1, \"b\": n + 1}", map[string]int{"a": 1, "b": 4}},
+		{"[]*Order{{ID: \"x\"}}[0].ID", "x"},
+		{"map[string][]Order{\"x\": {{ID: \"y\"}}}[\"x\"][0].ID", "y"},
+		{"[1, \"a\", n]", []interface{}{1, "a", 3}},
+		{"[]", []interface{}{}},
+		{"d.Tag([\"a\", \"b\"], {\"c\": n})", "[a b] map[c:3]"},
+		{"d.Tag([]string{}, map[string]int{})", "[] map[]"},
+		{"d.Sum([1, 2.5, n])", 6.5},
+		{"d.Floops([`{\"floops\": 5}`, {Floops: 2}])", 7},
+		{"new([]int)", new([]int)},
+	})
+	checkFailures(t, i, []string{
+		"[]int{\"a\"}",
+		"[]int{1: 2}",
+		"map[string]int{1}",
+		"map[string]int{\"a\": \"b\"}",
+		"map[[]int]int{}",
+		"[]Nope{}",
+		"{1}",
+		"d.Sum([\"a\"])",
+		"d.Sum({n: 1})",
+	})
+}
+
+type counter struct {
+	Count int
+}
+
+func (c *counter) Incr() int {
+	c.Count++
+	return c.Count
+}
+
+type holder struct {
+	C      counter
+	Counts []counter
+}
+
+func TestPointers(t *testing.T) {
+	i := newInterpreter()
+	h := &holder{Counts: []counter{{}, {Count: 10}}}
+	i.storeInHeap("h", h)
+	i.storeInHeap("s", &settable{Items: []int{1, 2, 3}})
+	i.storeInHeap("ov", Order{ID: "v"})
+	i.storeInHeap("hv", holder{})
+	i.storeInHeap("np", (*int)(nil))
+	i.RegisterGlobal("rc", counter{}, true)
+	i.RegisterType(Order{})
+	checkResults(t, i, []evalCase{
+		{"h.C.Incr()", 1},
+		{"h.Counts[1].Incr()", 11},
+		{"(&ov).CustomID(false)", "onum-v"},
+		{"p = &ov", nil},
+		{"p.ID = \"changed\"", "changed"},
+		{"ov.ID", "changed"},
+		{"ov.ID = \"direct\"", "direct"},
+		{"p.ID", "direct"},
+		{"ov = Order{ID: \"again\"}", nil},
+		{"p.ID", "again"},
+		{"type(ov)", "instructor.Order"},
+		{"id = &hv.C.Count", nil},
+		{"*id = 5", 5},
+		{"hv.C.Incr()", 6},
+		{"hv.C.Count", 6},
+		{"ov = 5", nil},
+		{"p.ID", "again"},
+		{"n = &5", nil},
+		{"*n + 1", 6},
+		{"*n = 7", 7},
+		{"*n", 7},
+		{"e = &s.Items[1]", nil},
+		{"*e = 20", 20},
+		{"s.Items[1]", 20},
+		{"c = &h.C", nil},
+		{"c.Incr() + (*c).Count", 4},
+		{"(*c).Count = 0", 0},
+		{"u = &Order{ID: \"new\"}", nil},
+		{"u.CustomID(false)", "onum-new"},
+	})
+	if h.C.Count != 0 || h.Counts[1].Count != 11 {
+		t.Errorf("Expected the pointer methods to change the real counters, got %#v", h)
+	}
+	if s := i.heap["s"].(*settable); s.Items[1] != 20 {
+		t.Errorf("Expected the slice element to be set through a pointer, got %v", s.Items)
+	}
+	checkFailures(t, i, []string{
+		"*5",
+		"*np",
+		"*np = 5",
+		"*ov = 5",
+		"*n = \"a\"",
+		"&s.hidden",
+		"&rc",
+		"&rc.Count",
+	})
+}
+
+func (c counter) Copy() counter {
+	return c
+}
+
+// pv and lim have pointer receiver methods that take arguments, which can name the variable they're called on
+type pv struct {
+	N int
+}
+
+func (p *pv) Same(o pv) bool {
+	return *p == o
+}
+
+type lim struct {
+	Calls int
+}
+
+func (l *lim) Name(s string) string {
+	l.Calls++
+	return s
+}
+
+func TestPointerReceivers(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("cv", counter{})
+	i.storeInHeap("p", pv{N: 1})
+	i.storeInHeap("v", lim{})
+	i.storeInHeap("hv", holder{Counts: []counter{{Count: 10}}})
+	i.storeInHeap("ov", Order{ID: "v", NumFloops: 2})
+	i.RegisterGlobal("rc", counter{}, true)
+	checkResults(t, i, []evalCase{
+		{"ov.CustomID(true)", "onum-v-2"},
+		{"cv.Incr()", 1},
+		{"cv.Incr() + cv.Incr()", 5},
+		{"cv.Count", 3},
+		{"hv.C.Incr()", 1},
+		{"hv.Counts[0].Incr()", 11},
+		{"cv.Copy().Incr()", 4},
+		{"rc.Incr()", 1},
+		{"rc.Incr()", 1},
+		{"(&cv).Copy().Count", 3},
+		{"p.Same(p)", true},
+		{"v.Name(type(v))", "instructor.lim"},
+		{"v.Calls", 1},
+	})
+	if cv := i.heapValue("cv").(counter); cv.Count != 3 {
+		t.Errorf("Expected the changes from Incr to be written back to cv, got %#v", cv)
+	}
+	if hv := i.heapValue("hv").(holder); hv.C.Count != 1 || hv.Counts[0].Count != 11 {
+		t.Errorf("Expected the changes from Incr to be written back to hv, got %#v", hv)
+	}
+	if rc := i.heapValue("rc").(counter); rc.Count != 0 {
+		t.Errorf("Expected the read only rc to be left alone, got %#v", rc)
+	}
+	if _, err := evalString(i, "cv.Nope()"); err == nil {
+		t.Errorf("Expected an error calling a method that doesn't exist")
+	}
+}
+
+func (d *directory) Label(prefix string, tags ...string) string {
+	return prefix + strings.Join(tags, ",")
+}
+
+func TestVariadicCalls(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("d", &directory{})
+	i.storeInHeap("xs", []string{"x", "y"})
+	i.RegisterFunc("join", strings.Join)
+	i.RegisterFunc("max", func(xs ...int) int {
+		m := 0
+		for _, x := range xs {
+			if x > m {
+				m = x
+			}
+		}
+		return m
+	})
+	checkResults(t, i, []evalCase{
+		{"d.Label(\"p:\")", "p:"},
+		{"d.Label(\"p:\", \"a\")", "p:a"},
+		{"d.Label(\"p:\", \"a\", \"b\", \"c\")", "p:a,b,c"},
+		{"d.Label(\"p:\", xs...)", "p:x,y"},
+		{"d.Label(\"p:\", [\"q\", xs[0]]...)", "p:q,x"},
+		{"d.Label(\"p:\", xs[1:]...)", "p:y"},
+		{"max(3, 9, 4)", 9},
+		{"max()", 0},
+		{"max([]int{5, 6}...)", 6},
+		{"join(xs, \"-\")", "x-y"},
+	})
+	for _, s := range []string{
+		"d.Label()",
+		"d.Label(\"p:\", xs)",
+		"d.Label(xs...)",
+		"d.Label(\"p:\", \"a\", xs...)",
+		"d.Label(\"p:\", d...)",
+		"join(xs)",
+		"join(xs, \"-\", \"+\")",
+		"join(xs...)",
+		"d.Child()",
+		"d.Child(1, 2)",
+	} {
+		_, err := evalString(i, s)
+		var panicErr *CallPanicError
+		if err == nil {
+			t.Errorf("%s: expected an error", s)
+		} else if errors.As(err, &panicErr) {
+			t.Errorf("%s: expected an error before calling, got a panic %s", s, err)
+		}
+	}
+}
+
+// waiter has methods that take the statement's context
+type waiter struct {
+	release chan struct{}
+}
+
+func (w *waiter) Deadline(ctx context.Context) bool {
+	_, ok := ctx.Deadline()
+	return ok
+}
+
+func (w *waiter) Echo(ctx context.Context, s string, n int) string {
+	return strings.Repeat(s, n)
+}
+
+func (w *waiter) Hang() {
+	<-w.release
+}
+
+// slow changes itself once it's released, long after the statement calling it has timed out
+type slow struct {
+	N        int
+	release  chan struct{}
+	finished chan struct{}
+}
+
+func (s *slow) Work() {
+	<-s.release
+	s.N++
+	close(s.finished)
+}
+
+func TestContexts(t *testing.T) {
+	i := newInterpreter()
+	i.out = ioutil.Discard
+	i.timeout = 20 * time.Millisecond
+	w := &waiter{release: make(chan struct{})}
+	defer close(w.release)
+	i.storeInHeap("w", w)
+	i.RegisterContextFinder("Order", func(ctx context.Context, id string) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("no deadline")
+		}
+		return &Order{ID: id}, nil
+	})
+	i.RegisterFinder("Slow", func(id string) (interface{}, error) {
+		w.Hang()
+		return nil, nil
+	})
+	for _, s := range []string{
+		"d = w.Deadline()",
+		"e = w.Echo(\"ab\", 2)",
+		"o = find(Order, \"x\")",
+	} {
+		if err := i.Evaluate(lexStatement(s)); err != nil {
+			t.Errorf("%s: unexpected error %s", s, err)
+		}
+	}
+	if i.heap["d"] != true || i.heap["e"] != "abab" || i.heap["o"].(*Order).ID != "x" {
+		t.Errorf("Expected the statement's context to be passed along, got %#v", i.heap)
+	}
+	if _, err := evalString(i, "w.Echo(\"ab\")"); err == nil {
+		t.Errorf("Expected the context parameter not to count as an argument")
+	}
+
+	for _, s := range []string{
+		"w.Hang()",
+		"find(Slow, \"x\")",
+	} {
+		err := i.Evaluate(lexStatement(s))
+		var cancelled *CancelledError
+		if !errors.As(err, &cancelled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected the statement to time out, got %v", s, err)
+		}
+	}
+
+	// An abandoned call only ever changes its own copy of a variable, which isn't written back
+	sv := slow{release: make(chan struct{}), finished: make(chan struct{})}
+	i.storeInHeap("s", sv)
+	if err := i.Evaluate(lexStatement("s.Work()")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected s.Work() to time out, got %v", err)
+	}
+	close(sv.release)
+	<-sv.finished
+	if r, err := evalString(i, "s.N"); err != nil || r != 0 {
+		t.Errorf("Expected s to be left alone by the abandoned call, got %#v %v", r, err)
+	}
+
+	// Even once the variable is addressable, the abandoned call doesn't get to change it
+	sv = slow{release: make(chan struct{}), finished: make(chan struct{})}
+	i.storeInHeap("a", sv)
+	if err := i.Evaluate(lexStatement("p = &a")); err != nil {
+		t.Errorf("Unexpected error taking the address of a: %s", err)
+	}
+	if err := i.Evaluate(lexStatement("a.Work()")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a.Work() to time out, got %v", err)
+	}
+	close(sv.release)
+	<-sv.finished
+	if r, err := evalString(i, "p.N"); err != nil || r != 0 {
+		t.Errorf("Expected a to be left alone by the abandoned call, got %#v %v", r, err)
+	}
+
+	// Nothing gets called once the statement has been cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	i.ctx = ctx
+	defer func() { i.ctx = nil }()
+	if _, err := evalString(i, "w.Echo(\"ab\", 2)"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled statement not to call anything, got %v", err)
+	}
+}
+
+func TestFinders(t *testing.T) {
+	i := newInterpreter()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	i.ctx = ctx
+	i.storeInHeap("c", &Order{ID: "c1", NumFloops: 3})
+	i.RegisterFinder("testRecord", lookup)
+	err := i.RegisterFinderFunc("Order", func(ctx context.Context, customer string, since time.Time) (*Order, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("no deadline")
+		}
+		if since.Year() != 2026 {
+			return nil, fmt.Errorf("no orders for %s since %s", customer, since)
+		}
+		return &Order{ID: customer + "-1"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.RegisterMultiFinder("Order", func(customer string, n int) []*Order {
+		orders := make([]*Order, n)
+		for k := range orders {
+			orders[k] = &Order{ID: fmt.Sprintf("%s-%d", customer, k)}
+		}
+		return orders
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.RegisterFunc("len", func(os []*Order) int { return len(os) })
+	checkResults(t, i, []evalCase{
+		{"find(testRecord, \"smedley@gmail.com\").Email", "smedley@mail.com"},
+		{"find(Order, c.ID, \"2026-01-01T00:00:00Z\").ID", "c1-1"},
+		{"find(Order, \"x\", \"2026-03-04T00:00:00Z\").ID", "x-1"},
+		{"len(findAll(Order, c.ID, c.NumFloops))", 3},
+		{"findAll(Order, \"x\", 2)[1].ID", "x-1"},
+	})
+	checkFailures(t, i, []string{
+		"find(Order, \"x\", \"2025-01-01T00:00:00Z\")",
+		"find(Order, \"x\")",
+		"find(Order, \"x\", 5)",
+		"find(testRecord, \"a\", \"b\")",
+		"find(testRecord)",
+		"findAll(testRecord, \"a\")",
+		"findAll(Order, 1)",
+	})
+
+	for _, fn := range []interface{}{
+		5,
+		func() {},
+		func() (int, int) { return 0, 0 },
+	} {
+		if err := i.RegisterFinderFunc("Bad", fn); err == nil {
+			t.Errorf("%T: expected an error registering it as a finder", fn)
+		}
+	}
+	if err := i.RegisterMultiFinder("Bad", func() *Order { return nil }); err == nil {
+		t.Errorf("Expected a multi finder to have to return a slice")
+	}
+}
@@ -3,6 +3,7 @@ package instructor
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 )
@@ -31,17 +32,34 @@ const (
 	TICK                      // 107: `
 	LBRACK                    // 108: [
 	RBRACK                    // 109: ]
+	COLON                     // 110: :
+	LBRACE                    // 111: {
+	RBRACE                    // 112: }
+	SPREAD                    // 113: ..., for spreading a slice into a variadic argument
 )
 
 // Reserved words - special operators and functions, pre-defined by the "runtime"
 const (
-	WORD Token = 200 + iota // 200: Placeholder, should be unused
-	FIND                    // 201: built in helper for locating structs, hacky
-	ADD                     // 202: Addition operator
-	SUB                     // 203: Subtraction operator
-	DIV                     // 204: Division operator
-	MULT                    // 205: Multiplication operator
-	MOD                     // 206: Modulo operator
+	WORD      Token = 200 + iota // 200: Placeholder, should be unused
+	FIND                         // 201: built in helper for locating structs, hacky
+	ADD                          // 202: Addition operator
+	SUB                          // 203: Subtraction operator
+	DIV                          // 204: Division operator
+	MULT                         // 205: Multiplication operator
+	MOD                          // 206: Modulo operator
+	EQUAL                        // 207: Equality operator, ==
+	NOTEQUAL                     // 208: Inequality operator, !=
+	LESS                         // 209: Less than operator, <
+	LESSEQ                       // 210: Less than or equal operator, <=
+	GREATER                      // 211: Greater than operator, >
+	GREATEREQ                    // 212: Greater than or equal operator, >=
+	AND                          // 213: Logical and operator, &&
+	OR                           // 214: Logical or operator, ||
+	NOT                          // 215: Logical not operator, !
+	NEW                          // 216: built in helper for allocating a registered type
+	MAP                          // 217: map types, in composite literals
+	ADDR                         // 218: Address operator, &
+	FINDALL                      // 219: built in helper for locating a list of structs
 )
 
 // Field and variable tokens
@@ -63,9 +81,21 @@ const eof = rune(0)
 
 //var validChar = regexp.MustCompile("^[\pL\pN\p{Pc}]*$")
 
+// Position is a location in the input text, with lines and columns counted from 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
 // Scanner is responsible for managing the parsing of a string of input text
 type scanner struct {
-	r *bufio.Reader
+	r    *bufio.Reader
+	pos  Position // position of the next rune to be read
+	prev Position // position before the last read, for unreading
 }
 
 type tokenBuffer struct {
@@ -83,7 +113,8 @@ type lexer struct {
 // newScanner returns a new instance of the Lexical Scanner
 func newScanner(r io.Reader) *scanner {
 	return &scanner{
-		r: bufio.NewReader(r),
+		r:   bufio.NewReader(r),
+		pos: Position{Line: 1, Column: 1},
 	}
 }
 
@@ -102,15 +133,41 @@ func (s *scanner) read() rune {
 	if err != nil {
 		return eof
 	}
+	s.prev = s.pos
+	if c == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
 	return c
 }
 
 func (s *scanner) unread() {
-	s.r.UnreadRune()
+	// If the last read hit the end of the input, there's nothing to rewind
+	if s.r.UnreadRune() == nil {
+		s.pos = s.prev
+	}
 }
 
-// Scan will scan the input text
+// accept reads the next rune if it is r, for spotting two character operators
+func (s *scanner) accept(r rune) bool {
+	if s.read() == r {
+		return true
+	}
+	s.unread()
+	return false
+}
+
+// Scan will scan the next fragment of the input text, noting the position it started at
 func (s *scanner) Scan() fragment {
+	pos := s.pos
+	f := s.scanFragment()
+	f.pos = pos
+	return f
+}
+
+func (s *scanner) scanFragment() fragment {
 	// Read the next rune
 	c := s.read()
 	// Return quick if it's already the end of file
@@ -143,6 +200,12 @@ func (s *scanner) Scan() fragment {
 		s.unread()
 		return s.scanNumber()
 	} else if c == '.' {
+		if s.accept('.') {
+			if s.accept('.') {
+				return fragment{token: SPREAD, text: "..."}
+			}
+			return fragment{token: WORD, text: ".."}
+		}
 		// Scan a word until the next period, eof, or lparen
 		return s.scanField()
 	}
@@ -150,7 +213,44 @@ func (s *scanner) Scan() fragment {
 	// Otherwise, see what kind of token it was
 	switch c {
 	case '=':
+		if s.accept('=') {
+			return fragment{token: EQUAL, text: "=="}
+		}
 		return fragment{token: ASSIGN, text: string(c)}
+	case '+':
+		return fragment{token: ADD, text: string(c)}
+	case '-':
+		return fragment{token: SUB, text: string(c)}
+	case '*':
+		return fragment{token: MULT, text: string(c)}
+	case '/':
+		return fragment{token: DIV, text: string(c)}
+	case '%':
+		return fragment{token: MOD, text: string(c)}
+	case '!':
+		if s.accept('=') {
+			return fragment{token: NOTEQUAL, text: "!="}
+		}
+		return fragment{token: NOT, text: string(c)}
+	case '<':
+		if s.accept('=') {
+			return fragment{token: LESSEQ, text: "<="}
+		}
+		return fragment{token: LESS, text: string(c)}
+	case '>':
+		if s.accept('=') {
+			return fragment{token: GREATEREQ, text: ">="}
+		}
+		return fragment{token: GREATER, text: string(c)}
+	case '&':
+		if s.accept('&') {
+			return fragment{token: AND, text: "&&"}
+		}
+		return fragment{token: ADDR, text: string(c)}
+	case '|':
+		if s.accept('|') {
+			return fragment{token: OR, text: "||"}
+		}
 	case '.':
 		return fragment{token: PERIOD, text: string(c)}
 	case '(':
@@ -163,9 +263,14 @@ func (s *scanner) Scan() fragment {
 		return fragment{token: LBRACK, text: string(c)}
 	case ']':
 		return fragment{token: RBRACK, text: string(c)}
-	default:
-		return fragment{token: WORD, text: string(c)}
+	case ':':
+		return fragment{token: COLON, text: string(c)}
+	case '{':
+		return fragment{token: LBRACE, text: string(c)}
+	case '}':
+		return fragment{token: RBRACE, text: string(c)}
 	}
+	return fragment{token: WORD, text: string(c)}
 }
 
 func (s *scanner) scanWhitespace() fragment {
@@ -254,20 +359,27 @@ func (s *scanner) scanRune() fragment {
 }
 
 func (s *scanner) scanField() fragment {
-	// Buffer in the current character, which should be a period
+	// The period has already been read, so this is the first character of the name
 	b := bytes.Buffer{}
-	b.WriteRune(s.read())
+	c := s.read()
+	if !isLetter(c) {
+		// There's no name after the period, like s. or s.+1, so leave whatever is there for the next
+		// scan, and let the parser complain about the empty field
+		s.unread()
+		return fragment{token: FIELD}
+	}
+	b.WriteRune(c)
 
 	for {
 		if c := s.read(); c == eof {
 			// It was the last field in the chain
 			break
-		} else if c == '.' || c == '(' || c == '[' || isWhitespace(c) {
+		} else if !isLetter(c) && !isDigit(c) {
 			// end of this field, start of another.
 			// or
 			// end of the method name
 			// or
-			// end of the field prior to an assign or equality check
+			// end of the field prior to an assign, comma, closing paren or bracket
 			// unread so the next scan gets the period.
 			s.unread()
 			break
@@ -302,6 +414,12 @@ func (s *scanner) scanWord() fragment {
 	switch word {
 	case "find":
 		return fragment{token: FIND, text: word}
+	case "findAll":
+		return fragment{token: FINDALL, text: word}
+	case "new":
+		return fragment{token: NEW, text: word}
+	case "map":
+		return fragment{token: MAP, text: word}
 	case "true", "false":
 		return fragment{token: BOOL, text: word}
 	}
@@ -338,7 +456,7 @@ func isWhitespace(c rune) bool {
 func isLetter(c rune) bool {
 	// Anything a-z, A-Z, or special characters
 	// TODO replace this with a proper regex, willya?
-	return (c >= 'a' && c < 'z') || (c >= 'A' && c <= 'Z') || c == '*'
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
 }
 
 func isDigit(c rune) bool {
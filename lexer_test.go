@@ -75,6 +75,72 @@ var cases = []LexerTestCase{
 			VARIABLE, FIELD, FIELD, LPAREN, INT, RPAREN, EOF,
 		},
 	},
+	{
+		statement: "c = o.Orders[0].CustomID(false)",
+		results: []Token{
+			VARIABLE, WS, ASSIGN, WS, VARIABLE, FIELD, LBRACK, INT, RBRACK, FIELD, LPAREN, BOOL, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "o.OrderList()[2].ID",
+		results: []Token{
+			VARIABLE, FIELD, LPAREN, RPAREN, LBRACK, INT, RBRACK, FIELD, EOF,
+		},
+	},
+	{
+		statement: "o.Dumb.DeepStuff5(o.Orders[2])",
+		results: []Token{
+			VARIABLE, FIELD, FIELD, LPAREN, VARIABLE, FIELD, LBRACK, INT, RBRACK, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "o.Dumb.DeepStuff2(o.Dumb.Yes, o.Stuff())",
+		results: []Token{
+			VARIABLE, FIELD, FIELD, LPAREN, VARIABLE, FIELD, FIELD, COMMA, WS, VARIABLE, FIELD, LPAREN, RPAREN, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "d = o.Orders[1]",
+		results: []Token{
+			VARIABLE, WS, ASSIGN, WS, VARIABLE, FIELD, LBRACK, INT, RBRACK, EOF,
+		},
+	},
+	{
+		statement: "o.Dumb.DeepStuff5(d)",
+		results: []Token{
+			VARIABLE, FIELD, FIELD, LPAREN, VARIABLE, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "o.Dumb.DeepStuff() * 2 >= 60000 && !o.Dumb.Yes",
+		results: []Token{
+			VARIABLE, FIELD, FIELD, LPAREN, RPAREN, WS, MULT, WS, INT, WS, GREATEREQ, WS, INT, WS, AND, WS, NOT, VARIABLE, FIELD, FIELD, EOF,
+		},
+	},
+	{
+		statement: "f = Flooper{Floops: 5}",
+		results: []Token{
+			VARIABLE, WS, ASSIGN, WS, VARIABLE, LBRACE, VARIABLE, COLON, WS, INT, RBRACE, EOF,
+		},
+	},
+	{
+		statement: "o.Dumb.DeepStuff4(&60)",
+		results: []Token{
+			VARIABLE, FIELD, FIELD, LPAREN, ADDR, INT, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "new(Flooper)",
+		results: []Token{
+			NEW, LPAREN, VARIABLE, RPAREN, EOF,
+		},
+	},
+	{
+		statement: "m = map[string][]int{\"a\": [1, 2]}",
+		results: []Token{
+			VARIABLE, WS, ASSIGN, WS, MAP, LBRACK, VARIABLE, RBRACK, LBRACK, RBRACK, VARIABLE, LBRACE, STRING, COLON, WS, LBRACK, INT, COMMA, WS, INT, RBRACK, RBRACE, EOF,
+		},
+	},
 }
 
 type testRecord struct {
@@ -123,6 +189,14 @@ func (n nestedProperty) DeepStuff4(i *int) int {
 	return *i + 1
 }
 
+func (n nestedProperty) DeepStuff5(o *Order) string {
+	return o.ID
+}
+
+func (t *testRecord) OrderList() []*Order {
+	return t.Orders
+}
+
 func (t *testRecord) Stuff() int {
 	return 500001
 }
@@ -160,6 +234,7 @@ func TestLexerCases(t *testing.T) {
 	i := newInterpreter()
 	i.RegisterFinder("testRecord", lookup)
 	i.RegisterConverter("Flooper", convertFloop)
+	i.RegisterType(Flooper{})
 	for _, c := range cases {
 		fmt.Println("-----")
 		r := strings.NewReader(c.statement)
@@ -0,0 +1,305 @@
+package instructor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// numericClass is how an operand takes part in arithmetic, after promotion
+type numericClass int
+
+const (
+	notNumeric numericClass = iota
+	intClass
+	uintClass
+	floatClass
+)
+
+func classify(v reflect.Value) numericClass {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intClass
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintClass
+	case reflect.Float32, reflect.Float64:
+		return floatClass
+	}
+	return notNumeric
+}
+
+// promote works out the class both sides of an arithmetic operation are converted to. Floats win out
+// over everything, and a signed int wins out over an unsigned one.
+func promote(l, r numericClass) numericClass {
+	if l == notNumeric || r == notNumeric {
+		return notNumeric
+	}
+	if l == floatClass || r == floatClass {
+		return floatClass
+	}
+	if l == intClass || r == intClass {
+		return intClass
+	}
+	return uintClass
+}
+
+func asInt(v reflect.Value) int64 {
+	if classify(v) == uintClass {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+func asUint(v reflect.Value) uint64 {
+	if classify(v) == intClass {
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+func asFloat(v reflect.Value) float64 {
+	switch classify(v) {
+	case intClass:
+		return float64(v.Int())
+	case uintClass:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// evaluateBinary applies a binary operator. && and || short circuit, so the right side is only
+// evaluated when it's needed.
+func (i *interpreter) evaluateBinary(n *binaryNode) (reflect.Value, error) {
+	l, err := i.evaluateOperator(n.left)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if n.op.token == AND || n.op.token == OR {
+		if l.Kind() != reflect.Bool {
+			return reflect.Value{}, fmt.Errorf("Error: %s is a %s, %s needs a bool", n.left, typeName(l), n.op.text)
+		}
+		if (n.op.token == AND) != l.Bool() {
+			// false && x, or true || x
+			return reflect.ValueOf(l.Bool()), nil
+		}
+		r, err := i.evaluateOperator(n.right)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if r.Kind() != reflect.Bool {
+			return reflect.Value{}, fmt.Errorf("Error: %s is a %s, %s needs a bool", n.right, typeName(r), n.op.text)
+		}
+		return reflect.ValueOf(r.Bool()), nil
+	}
+	r, err := i.evaluateOperator(n.right)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	switch n.op.token {
+	case EQUAL, NOTEQUAL:
+		eq, err := equal(n, l, r)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(eq == (n.op.token == EQUAL)), nil
+	case LESS, LESSEQ, GREATER, GREATEREQ:
+		return compare(n, l, r)
+	}
+	if n.op.token == ADD && l.Kind() == reflect.String && r.Kind() == reflect.String {
+		return reflect.ValueOf(l.String() + r.String()), nil
+	}
+	return arithmetic(n, l, r)
+}
+
+// evaluateUnary applies a unary operator
+func (i *interpreter) evaluateUnary(n *unaryNode) (reflect.Value, error) {
+	if n.op.token == ADDR {
+		return i.addressOf(n)
+	}
+	v, err := i.evaluateOperator(n.operand)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	switch n.op.token {
+	case MULT:
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("Error: Cannot dereference %s, it is nil", n.operand)
+			}
+			return v.Elem(), nil
+		}
+	case NOT:
+		if v.Kind() == reflect.Bool {
+			return reflect.ValueOf(!v.Bool()), nil
+		}
+	case SUB:
+		switch classify(v) {
+		case intClass, uintClass:
+			return reflect.ValueOf(int(-asInt(v))), nil
+		case floatClass:
+			return reflect.ValueOf(-v.Float()), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("Error: Cannot apply %s to %s, it is a %s", n.op.text, n.operand, typeName(v))
+}
+
+// addressOf applies the & operator. Anything addressable, like a property reached through a pointer or an
+// element of a slice, gives a pointer to the real thing. Variables, and anything stored in them by value, are
+// made addressable first. Anything else, like a literal or the result of a call, gives a pointer to a copy.
+func (i *interpreter) addressOf(n *unaryNode) (reflect.Value, error) {
+	if root := rootVariable(n.operand); root != nil {
+		_, isVariable := n.operand.(*identNode)
+		if isVariable || i.valueRoot(n.operand) != nil {
+			if i.isReadOnly(root.name) {
+				return reflect.Value{}, fmt.Errorf("Error: Cannot take the address of %s, %s is read only", n.operand, root)
+			}
+			i.addressVariable(root.name)
+		}
+	}
+	v, err := i.evaluateOperand(n.operand)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("Error: Cannot take the address of %s, it is nil", n.operand)
+	} else if !v.CanInterface() {
+		return reflect.Value{}, fmt.Errorf("Error: %s is unexported, and cannot be accessed", n.operand)
+	}
+	if v.CanAddr() {
+		return v.Addr(), nil
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p, nil
+}
+
+// evaluateOperator evaluates one side of an operator, down to the concrete value it holds
+func (i *interpreter) evaluateOperator(n node) (reflect.Value, error) {
+	v, err := i.evaluateOperand(n)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsValid() && !v.CanInterface() {
+		return reflect.Value{}, fmt.Errorf("Error: %s is unexported, and cannot be accessed", n)
+	}
+	return v, nil
+}
+
+func arithmetic(n *binaryNode, l, r reflect.Value) (reflect.Value, error) {
+	switch promote(classify(l), classify(r)) {
+	case intClass:
+		a, b := asInt(l), asInt(r)
+		switch n.op.token {
+		case ADD:
+			return reflect.ValueOf(int(a + b)), nil
+		case SUB:
+			return reflect.ValueOf(int(a - b)), nil
+		case MULT:
+			return reflect.ValueOf(int(a * b)), nil
+		case DIV, MOD:
+			if b == 0 {
+				return reflect.Value{}, fmt.Errorf("Error: Division by zero in %s", n)
+			}
+			if n.op.token == DIV {
+				return reflect.ValueOf(int(a / b)), nil
+			}
+			return reflect.ValueOf(int(a % b)), nil
+		}
+	case uintClass:
+		a, b := asUint(l), asUint(r)
+		switch n.op.token {
+		case ADD:
+			return reflect.ValueOf(uint(a + b)), nil
+		case SUB:
+			return reflect.ValueOf(uint(a - b)), nil
+		case MULT:
+			return reflect.ValueOf(uint(a * b)), nil
+		case DIV, MOD:
+			if b == 0 {
+				return reflect.Value{}, fmt.Errorf("Error: Division by zero in %s", n)
+			}
+			if n.op.token == DIV {
+				return reflect.ValueOf(uint(a / b)), nil
+			}
+			return reflect.ValueOf(uint(a % b)), nil
+		}
+	case floatClass:
+		a, b := asFloat(l), asFloat(r)
+		switch n.op.token {
+		case ADD:
+			return reflect.ValueOf(a + b), nil
+		case SUB:
+			return reflect.ValueOf(a - b), nil
+		case MULT:
+			return reflect.ValueOf(a * b), nil
+		case DIV:
+			return reflect.ValueOf(a / b), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("Error: Cannot apply %s to %s and %s, in %s", n.op.text, typeName(l), typeName(r), n)
+}
+
+// equal compares two values for == and !=. Numbers are compared after promotion, so 1 == 1.0, and
+// everything else has to be the same type, and comparable, like in Go
+func equal(n *binaryNode, l, r reflect.Value) (bool, error) {
+	switch promote(classify(l), classify(r)) {
+	case intClass:
+		return asInt(l) == asInt(r), nil
+	case uintClass:
+		return asUint(l) == asUint(r), nil
+	case floatClass:
+		return asFloat(l) == asFloat(r), nil
+	}
+	if !l.IsValid() || !r.IsValid() {
+		return !l.IsValid() && !r.IsValid(), nil
+	}
+	if l.Kind() == reflect.String && r.Kind() == reflect.String {
+		return l.String() == r.String(), nil
+	}
+	if l.Kind() == reflect.Bool && r.Kind() == reflect.Bool {
+		return l.Bool() == r.Bool(), nil
+	}
+	if l.Type() != r.Type() || !l.Type().Comparable() {
+		return false, fmt.Errorf("Error: Cannot compare %s and %s, in %s", typeName(l), typeName(r), n)
+	}
+	return l.Interface() == r.Interface(), nil
+}
+
+// compare applies one of the ordering operators to two numbers, or two strings
+func compare(n *binaryNode, l, r reflect.Value) (reflect.Value, error) {
+	// Work out which way the two sides lean, and then check that against the operator
+	var less, greater bool
+	switch promote(classify(l), classify(r)) {
+	case intClass:
+		less, greater = asInt(l) < asInt(r), asInt(l) > asInt(r)
+	case uintClass:
+		less, greater = asUint(l) < asUint(r), asUint(l) > asUint(r)
+	case floatClass:
+		less, greater = asFloat(l) < asFloat(r), asFloat(l) > asFloat(r)
+	default:
+		if l.Kind() != reflect.String || r.Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("Error: Cannot apply %s to %s and %s, in %s", n.op.text, typeName(l), typeName(r), n)
+		}
+		less, greater = l.String() < r.String(), l.String() > r.String()
+	}
+	switch n.op.token {
+	case LESS:
+		return reflect.ValueOf(less), nil
+	case LESSEQ:
+		return reflect.ValueOf(!greater), nil
+	case GREATER:
+		return reflect.ValueOf(greater), nil
+	}
+	return reflect.ValueOf(!less), nil
+}
+
+// typeName describes the type of a value for error messages, including when it's nil
+func typeName(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return v.Type().String()
+}
@@ -0,0 +1,51 @@
+package instructor
+
+import (
+	"testing"
+)
+
+type balance float64
+
+type account struct {
+	Balance balance
+	Count   uint
+	Name    string
+}
+
+func TestOperators(t *testing.T) {
+	i := newInterpreter()
+	i.storeInHeap("a", &account{Balance: 100, Count: 12, Name: "savings"})
+	checkResults(t, i, []evalCase{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"7 / 2", 3},
+		{"7 % 4", 3},
+		{"7 / 2.0", 3.5},
+		{"-3 + 1", -2},
+		{"a.Balance * 1.5", 150.0},
+		{"a.Count + 1", 13},
+		{"a.Count + a.Count", uint(24)},
+		{"a.Count - 20", -8},
+		{"a.Count > 10", true},
+		{"a.Count <= 10", false},
+		{"a.Balance == 100", true},
+		{"a.Name + \"-1\"", "savings-1"},
+		{"a.Name != \"savings\"", false},
+		{"\"abc\" < \"abd\"", true},
+		{"a.Count > 10 && a.Balance < 50", false},
+		{"a.Count > 10 || a.Missing", true},
+		{"!(a.Count > 10)", false},
+		{"1 < 2 == true", true},
+	})
+
+	checkFailures(t, i, []string{
+		"1 / 0",
+		"\"a\" * 2",
+		"a.Name > 5",
+		"!a.Count",
+		"a.Count && true",
+		"1.5 % 2",
+		"a == 5",
+	})
+}
@@ -0,0 +1,761 @@
+package instructor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// node is a single element of the expression tree built by the parser
+type node interface {
+	String() string
+	Pos() Position
+}
+
+// identNode is a bare variable name, which is resolved against the heap
+type identNode struct {
+	name string
+	pos  Position
+}
+
+// literalNode is a literal value, which is converted from its text when evaluated
+type literalNode struct {
+	token Token
+	text  string
+	pos   Position
+}
+
+// fieldNode is a property access on the result of target. When it is the fn of a
+// callNode, it is instead the name of the method being invoked
+type fieldNode struct {
+	target node
+	name   string
+	pos    Position
+}
+
+// indexNode is a bracketed index into the result of target
+type indexNode struct {
+	target node
+	index  node
+	pos    Position
+}
+
+// sliceNode is a slice expression on the result of target. Either bound can be nil, when it's left out
+type sliceNode struct {
+	target node
+	low    node
+	high   node
+	pos    Position
+}
+
+// callNode is an invocation of fn with the given arguments. When spread is set, the last argument
+// is a slice that's passed as the whole variadic parameter, like o.Tag(tags...)
+type callNode struct {
+	fn     node
+	args   []node
+	spread bool
+	pos    Position
+}
+
+// findNode is a call to the built in find helper, or findAll when all is set
+type findNode struct {
+	stype  string
+	args   []node
+	spread bool
+	all    bool
+	pos    Position
+}
+
+// newNode is a call to the built in new helper, which allocates a value of typ
+type newNode struct {
+	typ *typeSpec
+	pos Position
+}
+
+// compositeNode is a composite literal, like User{Name: "a"} or map[string]int{"a": 1}. typ is nil
+// when it's left out, like the {ID: "x"} in []Order{{ID: "x"}}, and comes from wherever the value goes
+type compositeNode struct {
+	typ   *typeSpec
+	elems []element
+	pos   Position
+}
+
+// listNode is a list literal without a type, like [1, 2, 3], which takes the type of wherever it's used
+type listNode struct {
+	elems []node
+	pos   Position
+}
+
+// typeSpec is a type written out in a statement, like User, []string, *Order or map[string]int
+type typeSpec struct {
+	kind reflect.Kind // Slice, Map or Ptr, or Invalid for a type referred to by name
+	name string
+	key  *typeSpec
+	elem *typeSpec
+}
+
+// element is a single entry in a composite literal. key is nil when the entry doesn't have one
+type element struct {
+	key   node
+	value node
+}
+
+// binaryNode applies an operator to the results of left and right
+type binaryNode struct {
+	op    fragment
+	left  node
+	right node
+	pos   Position
+}
+
+// unaryNode applies an operator to the result of operand
+type unaryNode struct {
+	op      fragment
+	operand node
+	pos     Position
+}
+
+// assignNode stores the result of rhs into lhs
+type assignNode struct {
+	lhs node
+	rhs node
+	pos Position
+}
+
+// multiAssignNode stores each of the results of the call rhs into the matching node of lhs
+type multiAssignNode struct {
+	lhs []node
+	rhs node
+	pos Position
+}
+
+func (n *identNode) String() string {
+	return n.name
+}
+
+func (n *literalNode) String() string {
+	switch n.token {
+	case STRING:
+		return fmt.Sprintf("%q", n.text)
+	case RUNE:
+		return "'" + n.text + "'"
+	}
+	return n.text
+}
+
+func (n *fieldNode) String() string {
+	return n.target.String() + "." + n.name
+}
+
+func (n *indexNode) String() string {
+	return n.target.String() + "[" + n.index.String() + "]"
+}
+
+func (n *sliceNode) String() string {
+	s := n.target.String() + "["
+	if n.low != nil {
+		s += n.low.String()
+	}
+	s += ":"
+	if n.high != nil {
+		s += n.high.String()
+	}
+	return s + "]"
+}
+
+func (n *callNode) String() string {
+	if n.spread {
+		return n.fn.String() + "(" + joinNodes(n.args) + "...)"
+	}
+	return n.fn.String() + "(" + joinNodes(n.args) + ")"
+}
+
+func (n *findNode) String() string {
+	name := "find("
+	if n.all {
+		name = "findAll("
+	}
+	args := []node{&identNode{name: n.stype}}
+	if n.spread {
+		return name + joinNodes(append(args, n.args...)) + "...)"
+	}
+	return name + joinNodes(append(args, n.args...)) + ")"
+}
+
+func (n *newNode) String() string {
+	return "new(" + n.typ.String() + ")"
+}
+
+func (n *compositeNode) String() string {
+	parts := make([]string, len(n.elems))
+	for j, e := range n.elems {
+		if e.key != nil {
+			parts[j] = e.key.String() + ": " + e.value.String()
+		} else {
+			parts[j] = e.value.String()
+		}
+	}
+	typ := ""
+	if n.typ != nil {
+		typ = n.typ.String()
+	}
+	return typ + "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (n *listNode) String() string {
+	return "[" + joinNodes(n.elems) + "]"
+}
+
+func (t *typeSpec) String() string {
+	switch t.kind {
+	case reflect.Slice:
+		return "[]" + t.elem.String()
+	case reflect.Map:
+		return "map[" + t.key.String() + "]" + t.elem.String()
+	case reflect.Ptr:
+		return "*" + t.elem.String()
+	}
+	return t.name
+}
+
+func (n *binaryNode) String() string {
+	return operandString(n.left) + " " + n.op.text + " " + operandString(n.right)
+}
+
+func (n *unaryNode) String() string {
+	return n.op.text + operandString(n.operand)
+}
+
+// operandString wraps nested operations in parens, so the order they're applied in is clear
+func operandString(n node) string {
+	switch n.(type) {
+	case *binaryNode, *unaryNode:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+func (n *assignNode) String() string {
+	return n.lhs.String() + " = " + n.rhs.String()
+}
+
+func (n *multiAssignNode) String() string {
+	return joinNodes(n.lhs) + " = " + n.rhs.String()
+}
+
+func (n *identNode) Pos() Position {
+	return n.pos
+}
+
+func (n *literalNode) Pos() Position {
+	return n.pos
+}
+
+func (n *fieldNode) Pos() Position {
+	return n.pos
+}
+
+func (n *indexNode) Pos() Position {
+	return n.pos
+}
+
+func (n *sliceNode) Pos() Position {
+	return n.pos
+}
+
+func (n *callNode) Pos() Position {
+	return n.pos
+}
+
+func (n *findNode) Pos() Position {
+	return n.pos
+}
+
+func (n *newNode) Pos() Position {
+	return n.pos
+}
+
+func (n *compositeNode) Pos() Position {
+	return n.pos
+}
+
+func (n *listNode) Pos() Position {
+	return n.pos
+}
+
+func (n *binaryNode) Pos() Position {
+	return n.pos
+}
+
+func (n *unaryNode) Pos() Position {
+	return n.pos
+}
+
+func (n *assignNode) Pos() Position {
+	return n.pos
+}
+
+func (n *multiAssignNode) Pos() Position {
+	return n.pos
+}
+
+func joinNodes(nodes []node) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parser is a recursive descent parser, which turns a statement from the lexer into
+// an expression tree. The grammar it currently understands is:
+//
+//	statement = expr { "," expr } [ "=" expr ] EOF
+//	expr      = unary { binary_op unary }
+//	unary     = { "-" | "!" | "&" | "*" } postfix
+//	postfix   = primary { FIELD | "[" expr "]" | "[" [ expr ] ":" [ expr ] "]" | "(" [ value { "," value } [ "..." ] ] ")" }
+//	primary   = VARIABLE | literal | composite | list | find | "new" "(" type ")" | "(" expr ")"
+//	composite = ( VARIABLE | "[" "]" type | "map" "[" type "]" type ) body
+//	body      = "{" [ element { "," element } [ "," ] ] "}"
+//	element   = [ value ":" ] value
+//	value     = expr | body
+//	list      = "[" [ value { "," value } [ "," ] ] "]"
+//	type      = VARIABLE | "*" type | "[" "]" type | "map" "[" type "]" type
+//	find      = ( "find" | "findAll" ) "(" VARIABLE { "," value } [ "..." ] ")"
+//
+// Binary operators follow Go's precedence, from highest to lowest:
+//
+//	Precedence  Operator
+//	5           *  /  %
+//	4           +  -
+//	3           ==  !=  <  <=  >  >=
+//	2           &&
+//	1           ||
+type parser struct {
+	s   statement
+	pos int
+}
+
+// newParser returns a parser over the statement, with any whitespace removed
+func newParser(s statement) *parser {
+	return &parser{s: cleanWhitespace(s)}
+}
+
+// parseStatement parses a full statement. An empty statement results in a nil node
+func parseStatement(s statement) (node, error) {
+	return newParser(s).parse()
+}
+
+// peek returns the next fragment without consuming it. Running off the end of the
+// statement is treated the same as hitting an EOF
+func (p *parser) peek() fragment {
+	if p.pos >= len(p.s) {
+		if len(p.s) > 0 {
+			return fragment{token: EOF, pos: p.s[len(p.s)-1].pos}
+		}
+		return fragment{token: EOF, pos: Position{Line: 1, Column: 1}}
+	}
+	return p.s[p.pos]
+}
+
+// next consumes and returns the next fragment
+func (p *parser) next() fragment {
+	f := p.peek()
+	if p.pos < len(p.s) {
+		p.pos++
+	}
+	return f
+}
+
+// expect consumes the next fragment, failing if it is not of the given token
+func (p *parser) expect(t Token, what string) (fragment, error) {
+	f := p.next()
+	if f.token != t {
+		return f, parseError(f, "expected %s but found %s", what, describeFragment(f))
+	}
+	return f, nil
+}
+
+func (p *parser) parse() (node, error) {
+	if p.peek().token == EOF {
+		return nil, nil
+	}
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	// A list of expressions can only be the left side of an assignment, like v, err = o.Load(5)
+	lhs := []node{n}
+	for p.peek().token == COMMA {
+		p.next()
+		if n, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		lhs = append(lhs, n)
+	}
+	if p.peek().token == ASSIGN {
+		eq := p.next()
+		rhs, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if len(lhs) > 1 {
+			n = &multiAssignNode{lhs: lhs, rhs: rhs, pos: eq.pos}
+		} else {
+			n = &assignNode{lhs: n, rhs: rhs, pos: eq.pos}
+		}
+	} else if len(lhs) > 1 {
+		f := p.next()
+		return nil, parseError(f, "expected = after %s but found %s", joinNodes(lhs), describeFragment(f))
+	}
+	if f := p.next(); f.token != EOF {
+		return nil, parseError(f, "unexpected %s after %s", describeFragment(f), n)
+	}
+	return n, nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	return p.parseBinary(1)
+}
+
+// parseBinary parses a chain of binary operations, with operators of at least the given precedence
+func (p *parser) parseBinary(minPrec int) (node, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec := precedence(op.token)
+		if prec < minPrec {
+			return n, nil
+		}
+		p.next()
+		// Everything on the right has to bind tighter, which keeps operators of the same precedence left associative
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		n = &binaryNode{op: op, left: n, right: right, pos: op.pos}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch op := p.peek(); op.token {
+	case SUB, NOT, ADDR, MULT:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand, pos: op.pos}, nil
+	}
+	return p.parsePostfix()
+}
+
+// precedence returns how tightly a binary operator binds, or 0 if the token isn't one
+func precedence(t Token) int {
+	switch t {
+	case MULT, DIV, MOD:
+		return 5
+	case ADD, SUB:
+		return 4
+	case EQUAL, NOTEQUAL, LESS, LESSEQ, GREATER, GREATEREQ:
+		return 3
+	case AND:
+		return 2
+	case OR:
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	// Keep applying field access, indexing and calls to whatever we've built so far
+	for {
+		switch f := p.peek(); f.token {
+		case FIELD:
+			p.next()
+			name := strings.TrimPrefix(f.text, ".")
+			if name == "" {
+				return nil, parseError(f, "expected a field or method name after %s.", n)
+			}
+			// Point at the name, rather than the period in front of it
+			pos := f.pos
+			pos.Column++
+			n = &fieldNode{target: n, name: name, pos: pos}
+		case LBRACK:
+			p.next()
+			if n, err = p.parseIndex(n, f); err != nil {
+				return nil, err
+			}
+		case LPAREN:
+			p.next()
+			args, spread, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			n = &callNode{fn: n, args: args, spread: spread, pos: n.Pos()}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// parseIndex parses either an index or a slice expression on target, after the opening bracket
+func (p *parser) parseIndex(target node, lbrack fragment) (node, error) {
+	var low, high node
+	var err error
+	if p.peek().token != COLON {
+		if low, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if p.peek().token == RBRACK {
+			p.next()
+			return &indexNode{target: target, index: low, pos: lbrack.pos}, nil
+		}
+	}
+	if _, err := p.expect(COLON, ": or ]"); err != nil {
+		return nil, err
+	}
+	if p.peek().token != RBRACK {
+		if high, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(RBRACK, "]"); err != nil {
+		return nil, err
+	}
+	return &sliceNode{target: target, low: low, high: high, pos: lbrack.pos}, nil
+}
+
+// parseArgs parses a comma separated list of expressions, up to and including the closing paren. It also
+// reports if the last one was followed by ..., to spread it into a variadic parameter
+func (p *parser) parseArgs() ([]node, bool, error) {
+	args := make([]node, 0)
+	if p.peek().token == RPAREN {
+		p.next()
+		return args, false, nil
+	}
+	for {
+		// Arguments take their type from the parameter, so they can leave it out of composite literals too
+		arg, err := p.parseValue()
+		if err != nil {
+			return nil, false, err
+		}
+		args = append(args, arg)
+		f := p.next()
+		if f.token == SPREAD {
+			// Only the last argument can be spread
+			_, err := p.expect(RPAREN, ") after ...")
+			return args, true, err
+		} else if f.token == RPAREN {
+			return args, false, nil
+		} else if f.token != COMMA {
+			return nil, false, parseError(f, "expected , or ) but found %s", describeFragment(f))
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	f := p.next()
+	switch {
+	case f.token == VARIABLE && p.peek().token == LBRACE:
+		return p.parseComposite(&typeSpec{name: f.text}, f.pos)
+	case f.token == MAP:
+		typ, err := p.parseType(f)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseComposite(typ, f.pos)
+	case f.token == LBRACK:
+		return p.parseList(f)
+	case f.token == VARIABLE:
+		return &identNode{name: f.text, pos: f.pos}, nil
+	case isValueToken(f.token):
+		return &literalNode{token: f.token, text: f.text, pos: f.pos}, nil
+	case f.token == FIND || f.token == FINDALL:
+		return p.parseFind(f)
+	case f.token == NEW:
+		return p.parseNew(f)
+	case f.token == LPAREN:
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RPAREN, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, parseError(f, "unexpected %s", describeFragment(f))
+}
+
+// parseFind parses the arguments to find or findAll, which are a type name followed by whatever arguments
+// its finder takes, like (Order, customerID, "2026-01-01")
+func (p *parser) parseFind(find fragment) (node, error) {
+	if _, err := p.expect(LPAREN, "( after "+find.text); err != nil {
+		return nil, err
+	}
+	stype, err := p.expect(VARIABLE, "a type name as the first argument to "+find.text)
+	if err != nil {
+		return nil, err
+	}
+	n := &findNode{stype: stype.text, args: []node{}, all: find.token == FINDALL, pos: find.pos}
+	f := p.next()
+	switch f.token {
+	case RPAREN:
+		return n, nil
+	case COMMA:
+		n.args, n.spread, err = p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, parseError(f, "expected , or ) but found %s", describeFragment(f))
+}
+
+// parseNew parses the argument to new, which is always a type
+func (p *parser) parseNew(f fragment) (node, error) {
+	if _, err := p.expect(LPAREN, "( after new"); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType(p.next())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(RPAREN, ")"); err != nil {
+		return nil, err
+	}
+	return &newNode{typ: typ, pos: f.pos}, nil
+}
+
+// parseType parses a type, starting from its first fragment f
+func (p *parser) parseType(f fragment) (*typeSpec, error) {
+	switch f.token {
+	case VARIABLE:
+		return &typeSpec{name: f.text}, nil
+	case MULT:
+		elem, err := p.parseType(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return &typeSpec{kind: reflect.Ptr, elem: elem}, nil
+	case LBRACK:
+		if _, err := p.expect(RBRACK, "] in a slice type"); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return &typeSpec{kind: reflect.Slice, elem: elem}, nil
+	case MAP:
+		if _, err := p.expect(LBRACK, "[ after map"); err != nil {
+			return nil, err
+		}
+		key, err := p.parseType(p.next())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RBRACK, "] after the key type"); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return &typeSpec{kind: reflect.Map, key: key, elem: elem}, nil
+	}
+	return nil, parseError(f, "expected a type but found %s", describeFragment(f))
+}
+
+// parseList parses either a list literal, or a composite literal of a slice type, after the opening bracket
+func (p *parser) parseList(lbrack fragment) (node, error) {
+	if p.peek().token == RBRACK {
+		p.next()
+		switch p.peek().token {
+		case VARIABLE, MULT, LBRACK, MAP:
+			// It's a slice type, like []string{"a", "b"}
+			elem, err := p.parseType(p.next())
+			if err != nil {
+				return nil, err
+			}
+			return p.parseComposite(&typeSpec{kind: reflect.Slice, elem: elem}, lbrack.pos)
+		}
+		return &listNode{elems: make([]node, 0), pos: lbrack.pos}, nil
+	}
+	n := &listNode{elems: make([]node, 0), pos: lbrack.pos}
+	for p.peek().token != RBRACK {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		n.elems = append(n.elems, value)
+		if p.peek().token != RBRACK {
+			if _, err := p.expect(COMMA, ", or ]"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return n, nil
+}
+
+// parseComposite parses the elements of a composite literal of typ, from the opening brace up to and
+// including the closing one
+func (p *parser) parseComposite(typ *typeSpec, pos Position) (node, error) {
+	if _, err := p.expect(LBRACE, "{"); err != nil {
+		return nil, err
+	}
+	n := &compositeNode{typ: typ, elems: make([]element, 0), pos: pos}
+	for p.peek().token != RBRACE {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		e := element{value: value}
+		if p.peek().token == COLON {
+			p.next()
+			if e.value, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+			e.key = value
+		}
+		n.elems = append(n.elems, e)
+		// Like in Go, the last element can be followed by a comma
+		if p.peek().token != RBRACE {
+			if _, err := p.expect(COMMA, ", or }"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return n, nil
+}
+
+// parseValue parses a value in a composite or list literal, which can also be a composite literal with its
+// type left out, like the {ID: "x"} in []Order{{ID: "x"}}
+func (p *parser) parseValue() (node, error) {
+	if f := p.peek(); f.token == LBRACE {
+		return p.parseComposite(nil, f.pos)
+	}
+	return p.parseExpr()
+}
+
+// parseError builds a ParseError pointing at the fragment f
+func parseError(f fragment, format string, args ...interface{}) error {
+	return &ParseError{Pos: f.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func describeFragment(f fragment) string {
+	if f.token == EOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", f.text)
+}
@@ -0,0 +1,94 @@
+package instructor
+
+import (
+	"errors"
+	"testing"
+)
+
+type ParserTestCase struct {
+	statement string
+	tree      string
+}
+
+var parserCases = []ParserTestCase{
+	{statement: "o", tree: "o"},
+	{statement: "50", tree: "50"},
+	{statement: "o.Dumb.Yes", tree: "o.Dumb.Yes"},
+	{statement: "o.Orders[1].CustomID(true)", tree: "o.Orders[1].CustomID(true)"},
+	{statement: "o = find(testRecord, \"smedley@gmail.com\")", tree: "o = find(testRecord, \"smedley@gmail.com\")"},
+	{statement: "a = b.Foo(c.Bar())", tree: "a = b.Foo(c.Bar())"},
+	{statement: "o.Items()[2].Name", tree: "o.Items()[2].Name"},
+	{statement: "o.Stuff2( false ,50 )", tree: "o.Stuff2(false, 50)"},
+	{statement: "(o.Dumb).Yes", tree: "o.Dumb.Yes"},
+	{statement: "o.Items[ 2 : 5 ]", tree: "o.Items[2:5]"},
+	{statement: "o.Items[:n-1][0]", tree: "o.Items[:n - 1][0]"},
+	{statement: "o.Items[-3:]", tree: "o.Items[-3:]"},
+	{statement: "v,err = o.Load( 5 )", tree: "v, err = o.Load(5)"},
+	{statement: "_, o.Items[1] = o.Pair()", tree: "_, o.Items[1] = o.Pair()"},
+	{statement: "u = User{Name:\"a\",Age: n+1,}", tree: "u = User{Name: \"a\", Age: n + 1}"},
+	{statement: "User{}.Name", tree: "User{}.Name"},
+	{statement: "p = new( User )", tree: "p = new(User)"},
+	{statement: "new(map[string][]*User)", tree: "new(map[string][]*User)"},
+	{statement: "[]string{\"a\", \"b\",}", tree: "[]string{\"a\", \"b\"}"},
+	{statement: "map[string]int{\"a\": 1, \"b\": n}", tree: "map[string]int{\"a\": 1, \"b\": n}"},
+	{statement: "[]Order{{ID: \"x\"}, {}}", tree: "[]Order{{ID: \"x\"}, {}}"},
+	{statement: "o.Tag([1, 2, a.B], [])", tree: "o.Tag([1, 2, a.B], [])"},
+	{statement: "[1, 2, 3][1:]", tree: "[1, 2, 3][1:]"},
+	{statement: "*p = &o.Items[1]", tree: "*p = &o.Items[1]"},
+	{statement: "a * *p", tree: "a * (*p)"},
+	{statement: "o.Tag(\"a\", xs ...)", tree: "o.Tag(\"a\", xs...)"},
+	{statement: "find(Order,c.ID , \"2026-01-01\")", tree: "find(Order, c.ID, \"2026-01-01\")"},
+	{statement: "os = findAll( Order )", tree: "os = findAll(Order)"},
+	{statement: "findAll(Order, {Status: \"open\"}, ids...)", tree: "findAll(Order, {Status: \"open\"}, ids...)"},
+}
+
+var parserErrorCases = []string{
+	"o.Stuff(",
+	"o.Orders[1",
+	"o.Orders[1:2:3]",
+	"o = ",
+	"find(\"x\", \"y\")",
+	"o.Stuff() o",
+	"o.Stuff(1 2)",
+	"a, b",
+	"a, = o.Pair()",
+	"User{Name: \"a\"",
+	"User{Name \"a\"}",
+	"new(\"User\")",
+	"new User",
+	"o.Tag(xs..., \"a\")",
+	"o.Tag(xs..)",
+	"[]{1}",
+	"map[string{}",
+	"[1, 2",
+	"[]string{\"a\" \"b\"}",
+	"find(Order \"x\")",
+	"findAll(\"Order\")",
+	"s.",
+	"s.Items.",
+	"s.+1",
+	"s.[0]",
+}
+
+func parseString(s string) (node, error) {
+	return parseStatement(lexStatement(s))
+}
+
+func TestParserCases(t *testing.T) {
+	for _, c := range parserCases {
+		n, err := parseString(c.statement)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.statement, err)
+			continue
+		}
+		if n.String() != c.tree {
+			t.Errorf("%s: got tree %s, expected %s", c.statement, n, c.tree)
+		}
+	}
+	for _, s := range parserErrorCases {
+		var parseErr *ParseError
+		if n, err := parseString(s); !errors.As(err, &parseErr) {
+			t.Errorf("%s: expected a ParseError, got tree %s, %v", s, n, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package instructor
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunScript evaluates a script of statements, one per line, without prompting. Blank lines and
+// lines starting with # or // are skipped, and a quit ends the script early. Results are written to
+// stdout, and errors to stderr.
+//
+// The script stops at the first statement that fails, returning its error, unless SetContinueOnError
+// has been turned on, in which case every failure is printed as it happens, and an error is returned
+// at the end if any of them failed.
+func (i *Instructor) RunScript(r io.Reader) error {
+	return runScript(i.interpreter, r, i.continueOnError)
+}
+
+func runScript(interp *interpreter, r io.Reader, continueOnError bool) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	failed := 0
+	for scanner.Scan() {
+		line++
+		stop, err := evaluateInput(interp, scanner.Text())
+		if err != nil {
+			where := fmt.Sprintf("line %d", line)
+			var p positioned
+			if errors.As(err, &p) {
+				where += fmt.Sprintf(", column %d", p.Position().Column)
+			}
+			if !continueOnError {
+				// Wrapped, so the typed error can still be picked out with errors.As
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			fmt.Fprintf(interp.errOut, "%s: %s\n", where, err.Error())
+			failed++
+		}
+		if stop {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading script: %s", err.Error())
+	}
+	if failed > 0 {
+		return fmt.Errorf("Error: %d statements in the script failed", failed)
+	}
+	return nil
+}
+
+// Run is an entry point for your sidecar binary, which decides what to do based on command line arguments,
+// typically os.Args[1:]. With no arguments, it enters the REPL. The supported flags are:
+//
+//	-f script.inst  runs the statements in the file, via RunScript
+//	-e 'statement'  runs the statement, via RunScript
+//	-continue       keeps running a script after a statement fails
+//	-timeout 30s    cancels any statement that runs for longer, via SetTimeout
+//
+// Any error returned should be treated as a failure, ex: by passing it to log.Fatal so the process exits
+// with a non-zero status.
+func (i *Instructor) Run(args []string) error {
+	fs := flag.NewFlagSet("instructor", flag.ContinueOnError)
+	file := fs.String("f", "", "Run the statements in this file, instead of starting the REPL")
+	stmt := fs.String("e", "", "Run this statement, instead of starting the REPL")
+	cont := fs.Bool("continue", false, "Keep running a script after a statement fails")
+	timeout := fs.Duration("timeout", 0, "Cancel any statement that runs for longer than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cont {
+		i.SetContinueOnError(true)
+	}
+	if *timeout > 0 {
+		i.SetTimeout(*timeout)
+	}
+	switch {
+	case *file != "" && *stmt != "":
+		return fmt.Errorf("Error: Only one of -f or -e can be given")
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return i.RunScript(f)
+	case *stmt != "":
+		return i.RunScript(strings.NewReader(*stmt))
+	}
+	return i.REPL()
+}
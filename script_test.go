@@ -0,0 +1,47 @@
+package instructor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testScript = `# Look up a record and poke at it
+o = find(testRecord, "smedley@gmail.com")
+// This one fails
+missing.Email
+o.Dumb.DeepStuff2(true, 5)
+`
+
+func TestRunScript(t *testing.T) {
+	for _, continueOnError := range []bool{false, true} {
+		i := New()
+		i.RegisterFinder("testRecord", lookup)
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		err := runScript(i.interpreter.withOutput(out, errOut), strings.NewReader(testScript), continueOnError)
+		if err == nil {
+			t.Errorf("continueOnError=%t: Expected the script to fail", continueOnError)
+		} else if !continueOnError {
+			var unknownErr *UnknownVariableError
+			if !strings.HasPrefix(err.Error(), "line 4, column 1: Error: ") || !errors.As(err, &unknownErr) {
+				t.Errorf("Expected the failing line in the error, wrapping an UnknownVariableError, got %s", err)
+			}
+		}
+		ran := strings.Contains(out.String(), "30061")
+		if ran != continueOnError {
+			t.Errorf("continueOnError=%t: Expected the last statement to have run: %t, got:\n%s", continueOnError, continueOnError, out)
+		}
+	}
+}
+
+func TestRunStatementFlag(t *testing.T) {
+	i := New()
+	if err := i.Run([]string{"-e", "5"}); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	if err := i.Run([]string{"-e", "nope"}); err == nil {
+		t.Errorf("Expected an error from an unknown variable")
+	}
+}
@@ -0,0 +1,30 @@
+package instructor
+
+import (
+	"net"
+)
+
+// Serve accepts connections on the listener, running a separate session for each one, until the
+// listener is closed. This lets you embed Instructor inside of your running application, and
+// attach to it over a unix socket or tcp, instead of bootstrapping a copy of your app in a sidecar.
+//
+// Each session gets its own heap, but they all share the finders and converters registered on the
+// Instructor, so be sure to register everything before calling Serve.
+//
+// Serve always returns a non-nil error, the one returned by the listener's Accept.
+func (i *Instructor) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go i.serveConn(conn)
+	}
+}
+
+// serveConn runs a session over a single connection, closing it once the session is over
+func (i *Instructor) serveConn(conn net.Conn) {
+	defer conn.Close()
+	runSession(i.interpreter.newSession(conn, conn), readerLines(conn, conn))
+}
@@ -0,0 +1,46 @@
+package instructor
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func runServedSession(t *testing.T, addr string, input string) string {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, input); err != nil {
+		t.Fatal(err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Unable to listen on loopback: %s", err)
+	}
+	i := New()
+	i.RegisterFinder("testRecord", lookup)
+	go i.Serve(l)
+	defer l.Close()
+
+	out := runServedSession(t, l.Addr().String(), "o = find(testRecord, \"smedley@gmail.com\")\no.Email\nquit\n")
+	if !strings.Contains(out, "smedley@mail.com") {
+		t.Errorf("Expected the first session to find the record, got:\n%s", out)
+	}
+	// A new session gets its own heap, so o shouldn't be there
+	out = runServedSession(t, l.Addr().String(), "o\n")
+	if strings.Contains(out, "smedley@mail.com") {
+		t.Errorf("Expected the second session to start with an empty heap, got:\n%s", out)
+	}
+}
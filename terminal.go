@@ -0,0 +1,68 @@
+package instructor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// terminal is the line editor behind the REPL, which handles history and tab completion
+type terminal struct {
+	line        *liner.State
+	historyFile string
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".instructor_history")
+}
+
+// newTerminal takes over the terminal, loading any history saved by previous sessions
+func newTerminal(interp *interpreter, historyFile string) *terminal {
+	t := &terminal{
+		line:        liner.NewLiner(),
+		historyFile: historyFile,
+	}
+	t.line.SetCtrlCAborts(true)
+	t.line.SetTabCompletionStyle(liner.TabPrints)
+	t.line.SetWordCompleter(interp.complete)
+	if historyFile != "" {
+		if f, err := os.Open(historyFile); err == nil {
+			t.line.ReadHistory(f)
+			f.Close()
+		}
+	}
+	return t
+}
+
+// readLine is a lineReader for the terminal
+func (t *terminal) readLine(prompt string) (string, error) {
+	input, err := t.line.Prompt(prompt)
+	if err == liner.ErrPromptAborted {
+		// Ctrl-C throws away whatever was typed, and starts a new line
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(input) != "" {
+		t.line.AppendHistory(input)
+	}
+	return input, nil
+}
+
+// close saves the history, and gives the terminal back
+func (t *terminal) close() {
+	if t.historyFile != "" {
+		// History can easily have sensitive things in it, so keep it private
+		if f, err := os.OpenFile(t.historyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err == nil {
+			t.line.WriteHistory(f)
+			f.Close()
+		}
+	}
+	t.line.Close()
+}